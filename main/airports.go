@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed airports.csv
+var embeddedAirports []byte
+
+// Airport - запись справочника аэропортов
+type Airport struct {
+	IATA        string
+	ICAO        string
+	CityCode    string // Код города (MOW, LON, ...), у города с одним аэропортом совпадает с IATA
+	CityRu      string
+	CityEn      string
+	NameRu      string
+	NameEn      string
+	CountryCode string
+	CountryRu   string
+	CountryEn   string
+	Lat         float64
+	Lon         float64
+	Timezone    string
+	Aliases     []string // Дополнительные написания для поиска: "бали", "спб", ...
+}
+
+// Location возвращает часовой пояс аэропорта или UTC, если он неизвестен
+func (a Airport) Location() *time.Location {
+	if loc, err := time.LoadLocation(a.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// City - город со всеми его аэропортами
+type City struct {
+	Code        string
+	NameRu      string
+	NameEn      string
+	CountryCode string
+	CountryRu   string
+	CountryEn   string
+	Airports    []string // Коды аэропортов в порядке справочника, основной - первый
+}
+
+// AirportDirectory - справочник аэропортов и городов
+type AirportDirectory struct {
+	byIATA map[string]Airport
+	cities map[string]*City
+	byName map[string]string // Нормализованное название или синоним -> код города
+}
+
+// airports - справочник, которым пользуются поиск городов и отображение названий.
+// По умолчанию загружается встроенный набор данных, main может заменить его
+// файлом из AIRPORTS_FILE до запуска бота и планировщика.
+var airports = mustLoadEmbeddedAirports()
+
+func mustLoadEmbeddedAirports() *AirportDirectory {
+	directory, err := parseAirports(bytes.NewReader(embeddedAirports))
+	if err != nil {
+		panic(fmt.Sprintf("встроенный справочник аэропортов повреждён: %v", err))
+	}
+	return directory
+}
+
+// LoadAirportDirectory загружает справочник из CSV-файла того же формата, что airports.csv
+func LoadAirportDirectory(path string) (*AirportDirectory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("открытие справочника аэропортов: %w", err)
+	}
+	defer file.Close()
+
+	return parseAirports(file)
+}
+
+func parseAirports(r io.Reader) (*AirportDirectory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 14
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("разбор справочника аэропортов: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("справочник аэропортов пуст")
+	}
+
+	directory := &AirportDirectory{
+		byIATA: make(map[string]Airport),
+		cities: make(map[string]*City),
+		byName: make(map[string]string),
+	}
+
+	// Первая строка - заголовок
+	for line, record := range records[1:] {
+		lat, errLat := strconv.ParseFloat(record[10], 64)
+		lon, errLon := strconv.ParseFloat(record[11], 64)
+		if errLat != nil || errLon != nil {
+			return nil, fmt.Errorf("строка %d: некорректные координаты", line+2)
+		}
+
+		airport := Airport{
+			IATA:        strings.ToUpper(record[0]),
+			ICAO:        strings.ToUpper(record[1]),
+			CityCode:    strings.ToUpper(record[2]),
+			CityRu:      record[3],
+			CityEn:      record[4],
+			NameRu:      record[5],
+			NameEn:      record[6],
+			CountryCode: strings.ToUpper(record[7]),
+			CountryRu:   record[8],
+			CountryEn:   record[9],
+			Lat:         lat,
+			Lon:         lon,
+			Timezone:    record[12],
+		}
+		if record[13] != "" {
+			airport.Aliases = strings.Split(record[13], ";")
+		}
+		if airport.IATA == "" || airport.CityCode == "" {
+			return nil, fmt.Errorf("строка %d: не указан код аэропорта или города", line+2)
+		}
+
+		directory.add(airport)
+	}
+
+	return directory, nil
+}
+
+func (d *AirportDirectory) add(airport Airport) {
+	d.byIATA[airport.IATA] = airport
+
+	city, exists := d.cities[airport.CityCode]
+	if !exists {
+		city = &City{
+			Code:        airport.CityCode,
+			NameRu:      airport.CityRu,
+			NameEn:      airport.CityEn,
+			CountryCode: airport.CountryCode,
+			CountryRu:   airport.CountryRu,
+			CountryEn:   airport.CountryEn,
+		}
+		d.cities[airport.CityCode] = city
+	}
+	city.Airports = append(city.Airports, airport.IATA)
+
+	for _, name := range append([]string{airport.CityRu, airport.CityEn}, airport.Aliases...) {
+		if normalized := normalizeCityName(name); normalized != "" {
+			d.byName[normalized] = airport.CityCode
+		}
+	}
+}
+
+// normalizeCityName приводит название к нижнему регистру и убирает лишние пробелы
+func normalizeCityName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// Airport возвращает аэропорт по коду IATA
+func (d *AirportDirectory) Airport(iata string) (Airport, bool) {
+	airport, ok := d.byIATA[strings.ToUpper(iata)]
+	return airport, ok
+}
+
+// City возвращает город по коду города или коду любого его аэропорта
+func (d *AirportDirectory) City(code string) (City, bool) {
+	code = strings.ToUpper(code)
+	if airport, ok := d.byIATA[code]; ok {
+		code = airport.CityCode
+	}
+	city, ok := d.cities[code]
+	if !ok {
+		return City{}, false
+	}
+	return *city, true
+}
+
+// Cities возвращает все города, упорядоченные по названию
+func (d *AirportDirectory) Cities() []City {
+	cities := make([]City, 0, len(d.cities))
+	for _, city := range d.cities {
+		cities = append(cities, *city)
+	}
+	sort.Slice(cities, func(i, j int) bool {
+		return cities[i].NameRu < cities[j].NameRu
+	})
+	return cities
+}
+
+// CitiesInCountry возвращает города страны, заданной кодом (ID) или
+// названием на русском или английском. Допускается начало названия: "таи".
+func (d *AirportDirectory) CitiesInCountry(country string) []City {
+	query := normalizeCityName(country)
+	if query == "" {
+		return nil
+	}
+
+	var cities []City
+	for _, city := range d.Cities() {
+		if strings.EqualFold(city.CountryCode, query) ||
+			strings.HasPrefix(strings.ToLower(city.CountryRu), query) ||
+			strings.HasPrefix(strings.ToLower(city.CountryEn), query) {
+			cities = append(cities, city)
+		}
+	}
+	return cities
+}
+
+// Lookup ищет город по точному названию, синониму, коду города или аэропорта.
+// Для кода аэропорта возвращается только он сам, для города - все его аэропорты.
+func (d *AirportDirectory) Lookup(query string) ([]string, string, bool) {
+	normalized := normalizeCityName(query)
+	upper := strings.ToUpper(normalized)
+
+	if airport, ok := d.byIATA[upper]; ok {
+		return []string{airport.IATA}, d.DisplayName(airport.IATA), true
+	}
+	if city, ok := d.cities[upper]; ok {
+		return append([]string{}, city.Airports...), city.NameRu, true
+	}
+	if code, ok := d.byName[normalized]; ok {
+		city := d.cities[code]
+		return append([]string{}, city.Airports...), city.NameRu, true
+	}
+	return nil, "", false
+}
+
+// ExpandCode возвращает аэропорты, по которым нужно искать для кода:
+// для кода города - все его аэропорты, для кода аэропорта - только его.
+// Коды, совпадающие с кодом города (BKK), трактуются как весь город.
+func (d *AirportDirectory) ExpandCode(code string) []string {
+	code = strings.ToUpper(code)
+	if city, ok := d.cities[code]; ok {
+		return append([]string{}, city.Airports...)
+	}
+	return []string{code}
+}
+
+// CityCode возвращает код города для кода аэропорта или города
+func (d *AirportDirectory) CityCode(code string) string {
+	code = strings.ToUpper(code)
+	if airport, ok := d.byIATA[code]; ok {
+		return airport.CityCode
+	}
+	return code
+}
+
+// HasSeveralAirports сообщает, что у города, к которому относится код, больше одного аэропорта
+func (d *AirportDirectory) HasSeveralAirports(code string) bool {
+	city, ok := d.cities[d.CityCode(code)]
+	return ok && len(city.Airports) > 1
+}
+
+// DisplayName возвращает название для отображения: для аэропорта в городе
+// с несколькими аэропортами - "Город (Аэропорт)", иначе название города
+func (d *AirportDirectory) DisplayName(code string) string {
+	code = strings.ToUpper(code)
+
+	if airport, ok := d.byIATA[code]; ok {
+		if city := d.cities[airport.CityCode]; city != nil && len(city.Airports) > 1 {
+			return fmt.Sprintf("%s (%s)", airport.CityRu, airport.NameRu)
+		}
+		return airport.CityRu
+	}
+	if city, ok := d.cities[code]; ok {
+		return city.NameRu
+	}
+	return code
+}
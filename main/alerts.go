@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+type AlertKind string
+
+const (
+	AlertPriceDrop  AlertKind = "drop" // Цена снизилась относительно прошлого наблюдения
+	AlertAllTimeLow AlertKind = "low"  // Цена ниже всех ранее наблюдённых
+)
+
+// AlertThresholds задает, какое снижение цены считается значимым.
+// Если оба порога нулевые, уведомление отправляется при любом снижении.
+type AlertThresholds struct {
+	MinDropRub       int  // Минимальное снижение в рублях
+	MinDropPercent   int  // Минимальное снижение в процентах
+	NotifyAllTimeLow bool // Сообщать о новом историческом минимуме независимо от порогов
+
+	// Срочные уведомления приходят сразу и со звуком, даже в тихие часы
+	// и при включённом дайджесте
+	UrgentAllTimeLow  bool // Новый исторический минимум - срочно
+	UrgentDropPercent int  // Снижение не меньше стольких процентов - срочно, 0 - не использовать
+}
+
+// PriceAlert - уведомление о снижении цены на маршрут и дату
+type PriceAlert struct {
+	Flight   Flight
+	Kind     AlertKind
+	OldPrice int // Минимальная цена при предыдущем наблюдении
+	LowPrice int // Исторический минимум до текущего поиска
+	Urgent   bool
+}
+
+// DropPercent возвращает снижение цены в процентах относительно OldPrice
+func (a PriceAlert) DropPercent() int {
+	if a.OldPrice <= 0 {
+		return 0
+	}
+	return (a.OldPrice - a.Flight.Price) * 100 / a.OldPrice
+}
+
+// DetectPriceDrops сравнивает найденные рейсы с наблюдениями, сделанными до before,
+// и возвращает уведомления по маршрутам/датам, где цена значимо снизилась.
+// Маршруты без истории не считаются снижением.
+func DetectPriceDrops(history *PriceHistory, flights []Flight, before time.Time, thresholds AlertThresholds) []PriceAlert {
+	if history == nil {
+		return nil
+	}
+
+	// Берем самый дешёвый рейс на каждый маршрут и дату
+	cheapest := make(map[string]Flight)
+	var order []string
+	for _, flight := range flights {
+		key := routeDateKey(flight.Origin, flight.Destination, flight.DepartureAt.Format("2006-01-02"))
+		current, exists := cheapest[key]
+		if !exists {
+			order = append(order, key)
+		}
+		if !exists || flight.Price < current.Price {
+			cheapest[key] = flight
+		}
+	}
+
+	var alerts []PriceAlert
+	for _, key := range order {
+		flight := cheapest[key]
+		oldPrice, lowPrice, found := previousPrices(history, flight, before)
+		if !found {
+			continue
+		}
+
+		if thresholds.NotifyAllTimeLow && flight.Price < lowPrice {
+			alert := PriceAlert{Flight: flight, Kind: AlertAllTimeLow, OldPrice: oldPrice, LowPrice: lowPrice}
+			alert.Urgent = thresholds.urgent(alert)
+			alerts = append(alerts, alert)
+			continue
+		}
+
+		alert := PriceAlert{Flight: flight, Kind: AlertPriceDrop, OldPrice: oldPrice, LowPrice: lowPrice}
+		if flight.Price < oldPrice && thresholds.significant(alert) {
+			alert.Urgent = thresholds.urgent(alert)
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].DropPercent() > alerts[j].DropPercent()
+	})
+	return alerts
+}
+
+// previousPrices находит минимальную цену последнего наблюдения до before
+// и исторический минимум для маршрута и даты рейса
+func previousPrices(history *PriceHistory, flight Flight, before time.Time) (oldPrice int, lowPrice int, found bool) {
+	observations := history.Observations(flight.Origin, flight.Destination, flight.DepartureAt.Format("2006-01-02"))
+
+	var lastObservedAt int64
+	for _, obs := range observations {
+		if obs.ObservedAt >= before.Unix() {
+			continue
+		}
+		if !found || obs.Price < lowPrice {
+			lowPrice = obs.Price
+		}
+		switch {
+		case !found || obs.ObservedAt > lastObservedAt:
+			lastObservedAt = obs.ObservedAt
+			oldPrice = obs.Price
+		case obs.ObservedAt == lastObservedAt && obs.Price < oldPrice:
+			oldPrice = obs.Price
+		}
+		found = true
+	}
+	return oldPrice, lowPrice, found
+}
+
+func (t AlertThresholds) significant(alert PriceAlert) bool {
+	drop := alert.OldPrice - alert.Flight.Price
+	if t.MinDropRub == 0 && t.MinDropPercent == 0 {
+		return drop > 0
+	}
+	if t.MinDropRub > 0 && drop >= t.MinDropRub {
+		return true
+	}
+	return t.MinDropPercent > 0 && alert.DropPercent() >= t.MinDropPercent
+}
+
+func (t AlertThresholds) urgent(alert PriceAlert) bool {
+	if t.UrgentAllTimeLow && alert.Kind == AlertAllTimeLow {
+		return true
+	}
+	return t.UrgentDropPercent > 0 && alert.DropPercent() >= t.UrgentDropPercent
+}
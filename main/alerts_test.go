@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestHistory(t *testing.T, retentionDays int) *PriceHistory {
+	t.Helper()
+	history, err := NewPriceHistory(filepath.Join(t.TempDir(), "history.jsonl"), retentionDays)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return history
+}
+
+// alertSummary - "вид:старая цена:минимум", у срочных с пометкой ":urgent"
+func alertSummary(alerts []PriceAlert) []string {
+	var result []string
+	for _, alert := range alerts {
+		summary := fmt.Sprintf("%s:%d:%d", alert.Kind, alert.OldPrice, alert.LowPrice)
+		if alert.Urgent {
+			summary += ":urgent"
+		}
+		result = append(result, summary)
+	}
+	return result
+}
+
+func TestDetectPriceDrops(t *testing.T) {
+	runStartedAt := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name       string
+		previous   []int // цены прошлых поисков, от старых к новым, по часу между ними
+		current    []int // цены, записанные в историю текущим поиском
+		price      int
+		thresholds AlertThresholds
+		want       []string
+	}{
+		{
+			name:  "маршрут без истории",
+			price: 9000,
+			want:  nil,
+		},
+		{
+			name:     "любое снижение при нулевых порогах",
+			previous: []int{10000},
+			price:    9900,
+			want:     []string{"drop:10000:10000"},
+		},
+		{
+			name:     "цена выросла",
+			previous: []int{10000},
+			price:    10100,
+			want:     nil,
+		},
+		{
+			name:       "снижение меньше порога в процентах",
+			previous:   []int{10000},
+			price:      9100,
+			thresholds: AlertThresholds{MinDropPercent: 10},
+			want:       nil,
+		},
+		{
+			name:       "снижение ровно на порог в процентах",
+			previous:   []int{10000},
+			price:      9000,
+			thresholds: AlertThresholds{MinDropPercent: 10},
+			want:       []string{"drop:10000:10000"},
+		},
+		{
+			name:       "достаточно порога в рублях",
+			previous:   []int{10000},
+			price:      9500,
+			thresholds: AlertThresholds{MinDropRub: 500, MinDropPercent: 20},
+			want:       []string{"drop:10000:10000"},
+		},
+		{
+			name:     "сравнение с последним наблюдением, а не с первым",
+			previous: []int{12000, 9000},
+			price:    9500,
+			want:     nil,
+		},
+		{
+			name:       "исторический минимум независимо от порогов",
+			previous:   []int{8000, 10000},
+			price:      7900,
+			thresholds: AlertThresholds{MinDropPercent: 50, NotifyAllTimeLow: true},
+			want:       []string{"low:10000:8000"},
+		},
+		{
+			name:       "снижение выше исторического минимума",
+			previous:   []int{8000, 10000},
+			price:      9000,
+			thresholds: AlertThresholds{NotifyAllTimeLow: true},
+			want:       []string{"drop:10000:8000"},
+		},
+		{
+			name:       "минимум без NotifyAllTimeLow - обычное снижение",
+			previous:   []int{8000, 10000},
+			price:      7900,
+			thresholds: AlertThresholds{MinDropPercent: 50},
+			want:       nil,
+		},
+		{
+			name:     "наблюдения текущего поиска не считаются прошлыми",
+			previous: []int{10000},
+			current:  []int{9000, 8500},
+			price:    9000,
+			want:     []string{"drop:10000:10000"},
+		},
+		{
+			name:    "история только из текущего поиска",
+			current: []int{9000},
+			price:   9000,
+			want:    nil,
+		},
+		{
+			name:       "срочное снижение",
+			previous:   []int{10000},
+			price:      7500,
+			thresholds: AlertThresholds{UrgentDropPercent: 25},
+			want:       []string{"drop:10000:10000:urgent"},
+		},
+		{
+			name:       "срочный исторический минимум",
+			previous:   []int{10000},
+			price:      9900,
+			thresholds: AlertThresholds{NotifyAllTimeLow: true, UrgentAllTimeLow: true, UrgentDropPercent: 25},
+			want:       []string{"low:10000:10000:urgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		history := newTestHistory(t, 30)
+		for i, price := range tt.previous {
+			observedAt := runStartedAt.Add(-time.Duration(len(tt.previous)-i) * time.Hour)
+			if err := history.Record([]Flight{tripFlight("OVB", "AER", "2026-11-20 10:00", price)}, observedAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, price := range tt.current {
+			if err := history.Record([]Flight{tripFlight("OVB", "AER", "2026-11-20 10:00", price)}, runStartedAt); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		flight := tripFlight("OVB", "AER", "2026-11-20 10:00", tt.price)
+		got := alertSummary(DetectPriceDrops(history, []Flight{flight}, runStartedAt, tt.thresholds))
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectPriceDropsUsesCheapestFlightAndSortsByDrop(t *testing.T) {
+	runStartedAt := time.Now().Truncate(time.Second)
+	history := newTestHistory(t, 30)
+	err := history.Record([]Flight{
+		tripFlight("OVB", "AER", "2026-11-20 10:00", 10000),
+		tripFlight("OVB", "AER", "2026-11-21 10:00", 10000),
+	}, runStartedAt.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := DetectPriceDrops(history, []Flight{
+		tripFlight("OVB", "AER", "2026-11-20 10:00", 9500),
+		tripFlight("OVB", "AER", "2026-11-20 18:00", 9000), // та же дата, дешевле
+		tripFlight("OVB", "AER", "2026-11-21 10:00", 7000),
+	}, runStartedAt, AlertThresholds{})
+
+	var got []string
+	for _, alert := range alerts {
+		got = append(got, fmt.Sprintf("%s=%d%%", alert.Flight.DepartureAt.Format("2006-01-02 15:04"), alert.DropPercent()))
+	}
+	want := []string{"2026-11-21 10:00=30%", "2026-11-20 18:00=10%"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("уведомления %v, ожидалось %v", got, want)
+	}
+}
@@ -1,68 +1,154 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"log"
-	"strconv"
 	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type Bot struct {
-	api          *tgbotapi.BotAPI
-	config       *AppConfig
-	flightSearch *FlightSearch
+	api           *tgbotapi.BotAPI
+	config        *AppConfig
+	flightSearch  *FlightSearch
+	subscriptions *SubscriptionStore
+	scheduler     *Scheduler
+
+	mu             sync.Mutex
+	pendingChoices map[chatUserKey]pendingCityChoice // Выбор города ждет тот, кто отправил команду
+	wizards        map[chatUserKey]*searchWizard
+	resultViews    map[resultViewKey]*resultView
+	topics         map[int64]int // Тема форума, в которой идет разговор с ботом
+	chatAdmins     map[chatUserKey]chatAdminStatus
 }
 
-func NewBot(config *AppConfig, flightSearch *FlightSearch) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(config.TelegramBotToken)
+func NewBot(config *AppConfig, flightSearch *FlightSearch, subscriptions *SubscriptionStore, scheduler *Scheduler) (*Bot, error) {
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(config.TelegramBotToken, telegramEndpoint(config.TelegramBotUrl))
 	if err != nil {
 		return nil, err
 	}
 
 	return &Bot{
-		api:          bot,
-		config:       config,
-		flightSearch: flightSearch,
+		api:           bot,
+		config:        config,
+		flightSearch:  flightSearch,
+		subscriptions: subscriptions,
+		scheduler:     scheduler,
+
+		pendingChoices: make(map[chatUserKey]pendingCityChoice),
+		wizards:        make(map[chatUserKey]*searchWizard),
+		resultViews:    make(map[resultViewKey]*resultView),
+		topics:         make(map[int64]int),
+		chatAdmins:     make(map[chatUserKey]chatAdminStatus),
 	}, nil
 }
 
+// telegramEndpoint - шаблон адреса Bot API. TELEGRAM_BOT_URL позволяет указать
+// свой сервер Bot API или локальную заглушку, например "http://localhost:8081"
+func telegramEndpoint(baseURL string) string {
+	if baseURL == "" {
+		return tgbotapi.APIEndpoint
+	}
+	if strings.Contains(baseURL, "%s") {
+		return baseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/bot%s/%s"
+}
+
 func (b *Bot) Start() {
 	log.Printf("Авторизован как %s", b.api.Self.UserName)
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	b.pollUpdates(func(update tgbotapi.Update, thread int) {
+		if chat := update.FromChat(); chat != nil {
+			b.setTopic(chat.ID, thread)
+		}
+
+		if update.CallbackQuery != nil {
+			b.handleCallback(update.CallbackQuery)
+			return
+		}
+
+		if update.EditedMessage != nil {
+			message := update.EditedMessage
+			if message.From != nil && b.addressedToBot(message) && b.isChatAllowed(message.Chat, message.From.ID) {
+				b.handleEditedMessage(message)
+			}
+			return
+		}
 
-	updates := b.api.GetUpdatesChan(u)
+		message := update.Message
+		if message == nil || message.From == nil {
+			return
+		}
 
-	for update := range updates {
-		if update.Message == nil {
-			continue
+		// В группе команды другим ботам и обычная переписка участников не для нас
+		if !b.addressedToBot(message) {
+			return
 		}
 
 		// Проверяем права пользователя
-		if !b.isUserAllowed(update.Message.From.ID) {
-			log.Printf("Проверяем права пользователя %d", update.Message.From.ID)
-			msg := tgbotapi.NewMessage(update.Message.Chat.ID, "❌ У вас нет прав для использования этого бота.")
-			b.api.Send(msg)
-			continue
+		if !b.isChatAllowed(message.Chat, message.From.ID) {
+			log.Printf("Проверяем права пользователя %d", message.From.ID)
+			if message.Chat.IsPrivate() || message.IsCommand() {
+				b.send(tgbotapi.NewMessage(message.Chat.ID, "❌ У вас нет прав для использования этого бота."))
+			}
+			return
+		}
+
+		// Текст без команды - ответ на шаг мастера поиска, если он открыт
+		if !message.IsCommand() {
+			if !b.handleWizardText(message) && message.Chat.IsPrivate() {
+				b.handleMessage(message)
+			}
+			return
 		}
 
-		// Обрабатываем команды
-		switch update.Message.Command() {
-		case "start":
-			b.handleStart(update.Message)
-		case "search", "find", "поиск":
-			b.handleSearch(update.Message)
-		case "status", "статус":
-			b.handleStatus(update.Message)
-		case "help", "помощь":
-			b.handleHelp(update.Message)
-		default:
-			b.handleUnknown(update.Message)
+		b.handleMessage(message)
+	})
+}
+
+// handleCallback обрабатывает нажатия на кнопки inline-клавиатуры
+func (b *Bot) handleCallback(callback *tgbotapi.CallbackQuery) {
+	var chat *tgbotapi.Chat
+	if callback.Message != nil {
+		chat = callback.Message.Chat
+	}
+	if !b.isChatAllowed(chat, callback.From.ID) {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "❌ Нет прав"))
+		return
+	}
+
+	action, value, _ := strings.Cut(callback.Data, ":")
+
+	// Выбор города и мастер поиска принадлежат тому, кто их начал:
+	// в группе остальные участники не могут нажимать чужие кнопки
+	if callback.Message != nil && (action == "city" || action == "wiz") {
+		owner, exists := b.keyboardOwner(callback.Message.Chat.ID, callback.Message.MessageID)
+		if exists && owner != callback.From.ID {
+			b.api.Request(tgbotapi.NewCallback(callback.ID, "🙅 Эти кнопки для другого участника"))
+			return
 		}
 	}
+
+	// Подтверждаем нажатие, чтобы у кнопки пропал индикатор загрузки
+	b.api.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	if callback.Message == nil {
+		return
+	}
+
+	switch action {
+	case "city":
+		b.handleCityChoice(callback.Message, callback.From.ID, value)
+	case "wiz":
+		b.handleWizardCallback(callback.Message, callback.From.ID, value)
+	case "res":
+		b.handleResultCallback(callback.Message, value)
+	}
 }
 
 func (b *Bot) isUserAllowed(userID int64) bool {
@@ -81,274 +167,246 @@ func (b *Bot) isUserAllowed(userID int64) bool {
 	return false
 }
 
-func (b *Bot) handleStart(message *tgbotapi.Message) {
-	text := `👋 <b>Бот поиска дешёвых авиабилетов</b>
+func (b *Bot) handleStart(message *tgbotapi.Message, args []string) {
+	query := b.flightSearch.DefaultQuery()
+
+	text := fmt.Sprintf(`👋 <b>Бот поиска дешёвых авиабилетов</b>
 
 <b>Команды:</b>
-/search - 🔍 Начать поиск билетов
-/status - 📊 Статус бота
-/help - ❓ Помощь
+%s
 
-<b>Направления:</b>
-• Новосибирск/Барнаул → Денпасар (Бали)
-• Макс. цена: 35,000 руб.
-• Поиск на 6 месяцев вперёд`
+<b>Направление по умолчанию:</b>
+• %s → %s
+• Макс. цена: %d руб.
+• Поиск на %d месяцев вперёд`,
+		commandsHelpHTML(),
+		strings.Join(query.Origins, "/"),
+		getCityName(query.Destination),
+		query.MaxPrice,
+		query.MonthsToSearch,
+	)
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
 	msg.ParseMode = "HTML"
-	b.api.Send(msg)
+	b.send(msg)
 }
 
-func (b *Bot) handleSearch(message *tgbotapi.Message) {
-	args := strings.Fields(message.Text)
+func (b *Bot) handleSearch(message *tgbotapi.Message, args []string) {
+	query := b.chatQuery(message.Chat.ID)
 
-	// 🆕 Если указано направление как второй параметр
+	// Аргументы проверяем до поиска города, чтобы не спрашивать город
+	// ради команды, которая все равно завершится ошибкой
 	if len(args) >= 2 {
-		if len(args) >= 3 {
-			destination := strings.ToUpper(args[1])
+		if err := applyPeriod(&query, args[1]); err != nil {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ %v\nНапример: <code>/search бали 3</code> или <code>/search бали 2026-12-20±3</code>", err))
+			return
+		}
+	}
+
+	// Направление и глубина поиска из аргументов действуют только на этот запрос
+	// и не меняют маршрут других пользователей и подписок
+	if len(args) >= 1 {
+		destination, ok := b.resolveArg(message, args, 0, (*Bot).handleSearch)
+		if !ok {
+			return // 🆕 Если город не найден, выходим
+		}
+		query.Destination = destination
+	}
 
-			monthsToSearch, err := strconv.Atoi(args[2])
-			if err != nil {
+	b.runSearch(message.Chat.ID, query)
+}
 
-			}
-			success := b.setDestinationByCityName(message.Chat.ID, destination, monthsToSearch)
-			if !success {
-				return // 🆕 Если город не найден, выходим
-			}
+// handleTrip ищет поездки туда-обратно: /trip город [ночей] [месяцев]
+func (b *Bot) handleTrip(message *tgbotapi.Message, args []string) {
+	query := b.chatQuery(message.Chat.ID)
+	query.RoundTrip = true
 
-			//b.setDestinationAndMonthsToSearch(message.Chat.ID, destination, monthsToSearch)
-		} else {
-			destination := strings.ToUpper(args[1])
-			b.setDestinationByCityName(message.Chat.ID, destination, b.config.MonthsToSearch)
-			//b.setDestination(message.Chat.ID, destination)
+	if len(args) >= 2 {
+		minNights, maxNights, err := ParseNightsRange(args[1])
+		if err != nil {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ %v\nНапример: <code>/trip бали 7-14 3</code>", err))
+			return
 		}
+		query.MinNights, query.MaxNights = minNights, maxNights
+	}
+
+	if len(args) >= 3 {
+		if err := applyPeriod(&query, args[2]); err != nil {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ %v\nНапример: <code>/trip бали 7-14 3</code> или <code>/trip бали 7-14 2026-12-20±3</code>", err))
+			return
+		}
+	}
+
+	if len(args) >= 1 {
+		destination, ok := b.resolveArg(message, args, 0, (*Bot).handleTrip)
+		if !ok {
+			return
+		}
+		query.Destination = destination
+	}
+
+	b.runSearch(message.Chat.ID, query)
+}
+
+// applyPeriod задает период поиска из аргумента команды: число месяцев
+// или фильтр дат, включая гибкую дату "2026-12-20±3"
+func applyPeriod(query *SearchQuery, arg string) error {
+	if months, err := ParseMonths(arg); err == nil {
+		query.MonthsToSearch = months
+		query.DateFilter = DateFilter{}
+		return nil
+	}
+
+	// Аргумент из одних цифр - это неудачное число месяцев, а не дата
+	if strings.Trim(arg, "0123456789") == "" {
+		_, err := ParseMonths(arg)
+		return err
+	}
+
+	filter, err := ParseDateFilter(arg)
+	if err != nil {
+		return err
+	}
+	query.DateFilter = filter
+	return nil
+}
+
+// runSearch выполняет поиск и отправляет результат в чат
+func (b *Bot) runSearch(chatID int64, query SearchQuery) {
+	route := "→"
+	if query.RoundTrip {
+		route = "⇄"
+	}
+
+	destination := getCityName(query.Destination)
+	if airports.HasSeveralAirports(query.Destination) {
+		destination += fmt.Sprintf(" (%s)", strings.Join(airports.ExpandCode(query.Destination), ", "))
+	}
+
+	period := fmt.Sprintf("%d мес.", query.MonthsToSearch)
+	if query.DateFilter.Enabled {
+		period = query.DateFilter.String()
+	}
+	if !query.Filters.Empty() {
+		period += "\n🎛 " + html.EscapeString(query.Filters.String())
 	}
 
 	// Отправляем сообщение о начале поиска
-	msg := tgbotapi.NewMessage(message.Chat.ID, "🔍 <b>Начинаю поиск билетов...</b>\nЭто займет несколько секунд.")
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"🔍 <b>Начинаю поиск билетов...</b>\n%s %s %s, %s\nЭто займет несколько секунд.",
+		strings.Join(query.Origins, "/"),
+		route,
+		destination,
+		period))
 	msg.ParseMode = "HTML"
-	b.api.Send(msg)
+	b.send(msg)
 
 	// Выполняем поиск
-	result, err := b.flightSearch.Search()
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+
+	result, err := b.flightSearch.Search(ctx, query)
 	if err != nil {
-		errorMsg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("❌ <b>Ошибка при поиске:</b>\n<code>%v</code>", err))
+		errorMsg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ <b>Ошибка при поиске:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
 		errorMsg.ParseMode = "HTML"
-		b.api.Send(errorMsg)
+		b.send(errorMsg)
 		return
 	}
 
 	// Отправляем результат
-	response := tgbotapi.NewMessage(message.Chat.ID, result)
-	response.ParseMode = "HTML"
-	response.DisableWebPagePreview = true
-	b.api.Send(response)
+	b.sendResult(chatID, result)
 }
 
-func (b *Bot) handleStatus(message *tgbotapi.Message) {
+func (b *Bot) handleStatus(message *tgbotapi.Message, args []string) {
+	query := b.chatQuery(message.Chat.ID)
+	defaultSpec, _ := b.scheduler.Defaults()
+
 	text := fmt.Sprintf(`📊 <b>Статус бота</b>
 
-<b>Направления поиска:</b>
+<b>Направление по умолчанию:</b>
 • %s → %s
 
 <b>Параметры:</b>
 • Макс. цена: %d руб.
 • Глубина поиска: %d месяцев
-• Авто-поиск: каждый день в 10:00
+• Фильтры: %s
+• Авто-поиск: %s (%s)
+
+<b>Ваши подписки:</b>
+%s
 
 Бот работает в штатном режиме 🟢`,
-		strings.Join(b.config.OriginIATA, "/"),
-		b.config.DestinationIATA,
-		b.config.MaxPrice,
-		b.config.MonthsToSearch,
+		strings.Join(query.Origins, "/"),
+		query.Destination,
+		query.MaxPrice,
+		query.MonthsToSearch,
+		html.EscapeString(query.Filters.String()),
+		html.EscapeString(DescribeSchedule(defaultSpec)),
+		html.EscapeString(b.chatTimezoneName(message.Chat.ID)),
+		b.formatSubscriptionsHTML(message.Chat.ID),
 	)
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
 	msg.ParseMode = "HTML"
-	b.api.Send(msg)
+	b.send(msg)
 }
 
-func (b *Bot) handleHelp(message *tgbotapi.Message) {
+// handleHelp показывает список команд, а с аргументом - подробную справку по команде
+func (b *Bot) handleHelp(message *tgbotapi.Message, args []string) {
+	if len(args) == 1 {
+		command, ok := findCommand(botCommands(), args[0])
+		if !ok {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Неизвестная команда <code>%s</code>. Список команд: /help",
+				html.EscapeString(args[0])))
+			return
+		}
+		b.replyHTML(message.Chat.ID, commandUsageHTML("/"+command.Name, command))
+		return
+	}
+
+	defaultSpec, _ := b.scheduler.Defaults()
 	text := `❓ <b>Помощь по боту</b>
 
 <b>Команды:</b>
-/search - Запустить поиск билетов
-/status - Показать статус бота
-/help - Эта справка
+` + commandsHelpHTML() + `
+
+Подробнее о команде: <code>/help команда</code>, например <code>/help origin</code>
 
 <b>Автоматический поиск:</b>
-Бот автоматически ищет билеты каждый день в 10:00 и присылает уведомления.
+Бот проверяет ваши подписки ` + html.EscapeString(DescribeSchedule(defaultSpec)) + ` (` + html.EscapeString(b.chatTimezoneName(message.Chat.ID)) + `) и присылает уведомления, если цены заметно снизились или появился новый минимум. Расписание подписок меняется командой /schedule.
 
 <b>Ручной поиск:</b>
 Используйте команду /search в любое время для запуска поиска.
 
-<b>Настройки:</b>
-Параметры поиска задаются в конфигурации бота.`
-
-	msg := tgbotapi.NewMessage(message.Chat.ID, text)
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
-}
-
-func (b *Bot) handleOrigin(message *tgbotapi.Message) {
-	args := strings.Fields(message.Text)
-
-	if len(args) >= 2 {
-		msg := tgbotapi.NewMessage(message.Chat.ID,
-			"❌ Укажите город вылета. Например: <code>/origin set москва</code>")
-		msg.ParseMode = "HTML"
-		b.api.Send(msg)
-		return
-	}
-	cityName := strings.Join(args[2:], " ")
-	b.setOrigin(message.Chat.ID, cityName)
-}
+<b>Даты в подписке:</b>
+<code>2026-03-01..2026-03-20</code> - диапазон
+<code>2026-03-01,2026-03-05</code> - конкретные даты
+<code>2026-03-10±3</code> - дата плюс-минус 3 дня, можно и в /search
 
-func (b *Bot) setDestination(chatID int64, destination string) {
-	oldDestination := b.config.DestinationIATA
-	b.flightSearch.SetDestination(destination)
+<b>В группе:</b>
+Команды можно писать с упоминанием бота: <code>/search@` + html.EscapeString(b.api.Self.UserName) + `</code>. Подписки, расписания и уведомления группы меняют её администраторы, уведомления приходят в ту тему, где создана подписка.`
 
-	msg := tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ <b>Направление изменено:</b>\n%s → %s\n➡️\n%s → %s",
-			strings.Join(b.config.OriginIATA, "/"),
-			getCityName(oldDestination),
-			strings.Join(b.config.OriginIATA, "/"),
-			getCityName(destination)))
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
+	b.replyHTML(message.Chat.ID, text)
 }
 
-func (b *Bot) setDestinationAndMonthsToSearch(chatID int64, destination string, monthsToSearch int) {
-	oldDestination := b.config.DestinationIATA
-	b.flightSearch.SetDestination(destination)
-	oldMonthsToSearch := b.config.MonthsToSearch
-	b.flightSearch.SetMonthsToSearch(monthsToSearch)
-
-	msg := tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ <b>Направление и глибина поиска изменены:</b>\n%s → %s\n➡️\n%s → %s</b>\n%d мес.→ %d мес.",
-			strings.Join(b.config.OriginIATA, "/"),
-			getCityName(oldDestination),
-			strings.Join(b.config.OriginIATA, "/"),
-			getCityName(destination),
-			b.config.MonthsToSearch,
-			oldMonthsToSearch))
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
-}
 func (b *Bot) handleUnknown(message *tgbotapi.Message) {
 	text := "❓ Неизвестная команда. Используйте /help для просмотра доступных команд."
 	msg := tgbotapi.NewMessage(message.Chat.ID, text)
-	b.api.Send(msg)
+	b.send(msg)
 }
 
-// SendMessage отправляет сообщение в указанный чат
-func (b *Bot) SendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "HTML"
-	msg.DisableWebPagePreview = true
-	msg.DisableNotification = true
-	b.api.Send(msg)
-}
-
-// Установка направления по названию города
-func (b *Bot) setDestinationByCityName(chatID int64, cityName string, monthsToSearch int) bool {
-	codes, foundCityName := FindAirportCode(cityName)
-
-	if codes == nil {
-		// 🆕 Город не найден, показываем подсказку
-		msg := tgbotapi.NewMessage(chatID,
-			fmt.Sprintf("❌ <b>Город '%s' не найден.</b>\n\n"+
-				"💡 <i>Используйте:</i>\n"+
-				"<code>/search бангкок</code> - поиск по названию\n"+
-				"<code>/search BKK</code> - поиск по коду аэропорта\n"+
-				"<code>/cities</code> - список доступных городов", cityName))
-		msg.ParseMode = "HTML"
-		b.api.Send(msg)
-		return false
-	}
-
-	// 🆕 Если найдено несколько аэропортов, берем первый
-	destination := codes[0]
-
-	b.flightSearch.SetMonthsToSearch(monthsToSearch)
-	oldDestination := b.config.DestinationIATA
-	b.flightSearch.SetDestination(destination)
-
-	var airportInfo string
-	if len(codes) > 1 {
-		airportInfo = fmt.Sprintf("\n🏢 Доступные аэропорты: %s", strings.Join(codes, ", "))
+// send отправляет сообщение и пишет ошибку отправки в лог
+func (b *Bot) send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var sent tgbotapi.Message
+	var err error
+	if msg, ok := c.(tgbotapi.MessageConfig); ok {
+		// Ответ уходит в тему форума, из которой пришла команда
+		sent, err = sendTelegramMessage(b.api, msg, b.topic(msg.ChatID))
+	} else {
+		sent, err = b.api.Send(c)
 	}
-
-	msg := tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ <b>Направление изменено:</b>\n%s → %s\n➡️\n%s → %s%s",
-			strings.Join(b.config.OriginIATA, "/"),
-			getCityName(oldDestination),
-			strings.Join(b.config.OriginIATA, "/"),
-			foundCityName,
-			airportInfo))
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
-	return true
-}
-
-// Команда для списка городов (заменяет /destinations)
-func (b *Bot) handleCitiesList(message *tgbotapi.Message) {
-	text := "🏙️ <b>Доступные города для поиска:</b>\n\n"
-	text += GetCityList()
-	text += "\n\n💡 <i>Используйте команду /search ГОРОД для поиска</i>\n"
-	text += "Например:\n"
-	text += "<code>/search бангкок</code> - поиск по названию\n"
-	text += "<code>/search BKK</code> - поиск по коду аэропорта\n"
-	text += "<code>/search сидней</code> - поиск в Сидней\n"
-	text += "<code>/search</code> - поиск в текущее направление"
-
-	msg := tgbotapi.NewMessage(message.Chat.ID, text)
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
-}
-
-// 🆕 ДОБАВЛЕНО: справка по команде origin
-func (b *Bot) setOrigin(chatID int64, cityName string) bool {
-	codes, _ := FindOriginAirportCode(cityName)
-
-	if codes == nil {
-		msg := tgbotapi.NewMessage(chatID,
-			fmt.Sprintf("❌ <b>Город вылета '%s' не найден.</b>\n\n"+
-				"💡 <i>Используйте:</i>\n"+
-				"<code>/origin list</code> - список доступных городов\n"+
-				"<code>/origin set москва</code> - установить Москву", cityName))
-		msg.ParseMode = "HTML"
-		b.api.Send(msg)
-		return false
-	}
-	origin := codes[0]
-	oldOrigins := make([]string, len(b.config.OriginIATA))
-	copy(oldOrigins, b.config.OriginIATA)
-	b.flightSearch.SetOriginIATA(origin)
-
-	var originInfo string
-	if len(codes) > 1 {
-		originInfo = fmt.Sprintf("\n🏢 Доступные аэропорты: %s", strings.Join(codes, ", "))
-	}
-	msg := tgbotapi.NewMessage(chatID,
-		fmt.Sprintf("✅ <b>Город вылета изменен:</b>\n%s → %s\n➡️\n%s → %s%s",
-			strings.Join(oldOrigins, "/"),
-			getCityName(b.config.DestinationIATA),
-			origin,
-			getCityName(b.config.DestinationIATA),
-			originInfo))
-	msg.ParseMode = "HTML"
-	b.api.Send(msg)
-	return true
-}
-
-func FindOriginAirportCode(cityName string) ([]string, string) {
-	normalized := strings.ToLower(strings.TrimSpace(cityName))
-
-	// Прямой поиск test
-	if codes, exists := CityAirports[normalized]; exists {
-		return codes, getCityName(codes[0])
+	if err != nil {
+		log.Printf("Ошибка отправки в Telegram: %v", err)
 	}
-	return nil, ""
-
+	return sent, err
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pendingCityChoiceTTL - сколько ждать выбора города из предложенных вариантов
+const pendingCityChoiceTTL = 10 * time.Minute
+
+// pendingCityChoice - команда, которая ждет уточнения города от отправившего
+// её пользователя. После выбора retry выполняет ее заново с кодом выбранного города.
+type pendingCityChoice struct {
+	messageID int // Сообщение с кнопками вариантов
+	retry     func(code string)
+	expiresAt time.Time
+}
+
+// resolveCity находит код аэропорта или города по названию из аргумента команды.
+// Если город не найден, отправляет подсказку; если подходит несколько городов,
+// предлагает выбрать нужный кнопками и после выбора вызывает retry.
+// В обоих случаях возвращает false.
+func (b *Bot) resolveCity(message *tgbotapi.Message, cityName string, retry func(code string)) (string, bool) {
+	matches := airports.SearchCities(cityName)
+
+	if len(matches) == 0 {
+		// 🆕 Город не найден, показываем подсказку
+		b.replyHTML(message.Chat.ID,
+			fmt.Sprintf("❌ <b>Город '%s' не найден.</b>\n\n"+
+				"💡 <i>Используйте:</i>\n"+
+				"<code>/search бангкок</code> - поиск по названию\n"+
+				"<code>/search BKK</code> - поиск по коду аэропорта\n"+
+				"<code>/cities</code> - список доступных городов", html.EscapeString(cityName)))
+		return "", false
+	}
+
+	if Ambiguous(matches) {
+		b.askCityChoice(message, cityName, matches, retry)
+		return "", false
+	}
+
+	// Для города с несколькими аэропортами ищем по всем его аэропортам
+	if len(matches[0].Codes) > 1 {
+		return matches[0].City.Code, true
+	}
+	return matches[0].Codes[0], true
+}
+
+// resolveArg - resolveCity для аргумента args[index]: после выбора города
+// повторяет команду handler с кодом города на месте названия
+func (b *Bot) resolveArg(message *tgbotapi.Message, args []string, index int, handler commandHandler) (string, bool) {
+	return b.resolveCity(message, args[index], func(code string) {
+		retried := append([]string{}, args...)
+		retried[index] = code
+		handler(b, message, retried)
+	})
+}
+
+// askCityChoice предлагает выбрать город из близких по написанию вариантов
+func (b *Bot) askCityChoice(message *tgbotapi.Message, cityName string, matches []CityMatch, retry func(code string)) {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, match := range matches {
+		label := fmt.Sprintf("%s, %s (%s)", match.City.NameRu, match.City.CountryRu, strings.Join(match.Codes, ", "))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "city:"+match.City.Code),
+		))
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("🤔 <b>Какой город вы имели в виду под '%s'?</b>", html.EscapeString(cityName)))
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	sent, err := b.send(msg)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.pendingChoices[chatUserKey{chatID: message.Chat.ID, userID: message.From.ID}] = pendingCityChoice{
+		messageID: sent.MessageID,
+		retry:     retry,
+		expiresAt: time.Now().Add(pendingCityChoiceTTL),
+	}
+	b.mu.Unlock()
+}
+
+// handleCityChoice выполняет отложенную команду пользователя userID с выбранным городом
+func (b *Bot) handleCityChoice(message *tgbotapi.Message, userID int64, cityCode string) {
+	key := chatUserKey{chatID: message.Chat.ID, userID: userID}
+
+	b.mu.Lock()
+	pending, exists := b.pendingChoices[key]
+	exists = exists && pending.messageID == message.MessageID
+	if exists {
+		delete(b.pendingChoices, key)
+	}
+	b.mu.Unlock()
+
+	// Убираем кнопки, чтобы нельзя было выбрать повторно
+	b.api.Request(tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID,
+		tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+
+	if !exists || time.Now().After(pending.expiresAt) {
+		b.replyHTML(message.Chat.ID, "⌛ Выбор устарел, повторите команду.")
+		return
+	}
+
+	pending.retry(cityCode)
+}
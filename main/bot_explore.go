@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleExplore ищет самые дешёвые направления из городов вылета: /explore [бюджет] [месяц[..месяц]]
+func (b *Bot) handleExplore(message *tgbotapi.Message, args []string) {
+	query := b.chatQuery(message.Chat.ID)
+	budget := query.MaxPrice
+	months := searchMonths(time.Now(), 1)
+
+	// Число - бюджет, остальное - месяц или диапазон месяцев, порядок аргументов не важен
+	for _, arg := range args {
+		if price, err := strconv.Atoi(arg); err == nil {
+			if price <= 0 {
+				b.replyHTML(message.Chat.ID, "❌ Бюджет должен быть больше нуля")
+				return
+			}
+			budget = price
+			continue
+		}
+
+		value, err := ParseExploreMonths(arg, time.Now())
+		if err != nil {
+			b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+			return
+		}
+		months = value
+	}
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf(
+		"🔍 <b>Ищу, куда полететь...</b>\nИз: %s, месяцы: %s, до %d₽",
+		strings.Join(query.Origins, "/"), formatMonthRange(months), budget))
+
+	ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+	defer cancel()
+
+	result, err := b.flightSearch.Explore(ctx, query, months, budget)
+	if errors.Is(err, errExploreUnsupported) {
+		b.replyHTML(message.Chat.ID, "❌ Ни один из поставщиков цен не умеет искать по всем направлениям")
+		return
+	}
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Ошибка при поиске:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+
+	b.replyHTML(message.Chat.ID, FormatExploreHTML(result))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatQuery - параметры поиска по умолчанию с настройками чата
+func (b *Bot) chatQuery(chatID int64) SearchQuery {
+	return b.subscriptions.Settings(chatID).Query(b.flightSearch.DefaultQuery())
+}
+
+// filterHandler меняет один фильтр чата: /filter <имя> значение.
+// Пока чат не менял фильтры, за основу берутся фильтры по умолчанию.
+func filterHandler(name string) commandHandler {
+	return func(b *Bot, message *tgbotapi.Message, args []string) {
+		filters := b.chatQuery(message.Chat.ID).Filters
+
+		// Списки можно перечислять и через пробел: "/filter airlines SU S7"
+		if err := filters.Set(name, strings.Join(args, ",")); err != nil {
+			b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+			return
+		}
+		err := b.subscriptions.UpdateSettings(message.Chat.ID, func(settings *ChatSettings) {
+			settings.Filters = &filters
+		})
+		if err != nil {
+			b.replyHTML(message.Chat.ID, "❌ Не удалось сохранить фильтры: "+html.EscapeString(err.Error()))
+			return
+		}
+
+		b.replyHTML(message.Chat.ID, "✅ <b>Фильтры рейсов чата:</b>\n"+html.EscapeString(filters.String()))
+	}
+}
+
+// handleFilterReset возвращает фильтры чата к фильтрам по умолчанию
+func (b *Bot) handleFilterReset(message *tgbotapi.Message, args []string) {
+	err := b.subscriptions.UpdateSettings(message.Chat.ID, func(settings *ChatSettings) {
+		settings.Filters = nil
+	})
+	if err != nil {
+		b.replyHTML(message.Chat.ID, "❌ Не удалось сохранить фильтры: "+html.EscapeString(err.Error()))
+		return
+	}
+
+	b.replyHTML(message.Chat.ID, "🗑 <b>Фильтры чата сброшены, действуют фильтры по умолчанию:</b>\n"+
+		html.EscapeString(b.flightSearch.DefaultQuery().Filters.String()))
+}
+
+// handleFilterList показывает фильтры рейсов чата
+func (b *Bot) handleFilterList(message *tgbotapi.Message, args []string) {
+	filters := b.chatQuery(message.Chat.ID).Filters
+	source := "по умолчанию"
+	if b.subscriptions.Settings(message.Chat.ID).Filters != nil {
+		source = "чата"
+	}
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf("🎛 <b>Фильтры рейсов %s:</b>\n%s\n\n"+
+		"💡 <code>/filter transfers 0</code>, <code>/filter layover 4ч</code>, <code>/filter departure 06:00-23:00</code>, "+
+		"<code>any</code> снимает фильтр. Все фильтры: <code>/help filter</code>",
+		source, html.EscapeString(filters.String())))
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatAdminTTL - сколько помнить, является ли участник администратором группы
+const chatAdminTTL = 5 * time.Minute
+
+// groupAnonymousBotID - от имени этого пользователя приходят сообщения
+// анонимных администраторов группы
+const groupAnonymousBotID = 1087968824
+
+// topicMessage - поля сообщения о темах форума, которых нет в telegram-bot-api v5.5.1
+type topicMessage struct {
+	MessageThreadID int  `json:"message_thread_id"`
+	IsTopicMessage  bool `json:"is_topic_message"`
+}
+
+// topicUpdate - темы сообщений обновления, разбираются из того же ответа getUpdates
+type topicUpdate struct {
+	Message       *topicMessage `json:"message"`
+	EditedMessage *topicMessage `json:"edited_message"`
+	CallbackQuery *struct {
+		Message *topicMessage `json:"message"`
+	} `json:"callback_query"`
+}
+
+// thread возвращает тему форума, в которой пришло обновление, 0 - без темы
+func (u topicUpdate) thread() int {
+	message := u.Message
+	if message == nil {
+		message = u.EditedMessage
+	}
+	if message == nil && u.CallbackQuery != nil {
+		message = u.CallbackQuery.Message
+	}
+	if message == nil || !message.IsTopicMessage {
+		return 0
+	}
+	return message.MessageThreadID
+}
+
+// chatAdminStatus - закэшированный ответ getChatMember
+type chatAdminStatus struct {
+	admin     bool
+	checkedAt time.Time
+}
+
+type chatUserKey struct {
+	chatID int64
+	userID int64
+}
+
+// pollUpdates получает обновления long polling'ом и вызывает handle для каждого
+// вместе с темой форума. Обновления обрабатываются по одному, поэтому тема
+// текущего обновления определяет, куда уйдут ответы бота в этот чат.
+func (b *Bot) pollUpdates(handle func(update tgbotapi.Update, thread int)) {
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+
+	for {
+		resp, err := b.api.Request(config)
+		if err != nil {
+			log.Printf("Ошибка получения обновлений: %v, повтор через 3 секунды", err)
+			time.Sleep(3 * time.Second)
+			continue
+		}
+
+		var updates []tgbotapi.Update
+		var topics []topicUpdate
+		if err := json.Unmarshal(resp.Result, &updates); err != nil {
+			log.Printf("Ошибка разбора обновлений: %v", err)
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		if err := json.Unmarshal(resp.Result, &topics); err != nil || len(topics) != len(updates) {
+			topics = make([]topicUpdate, len(updates))
+		}
+
+		for i, update := range updates {
+			if update.UpdateID >= config.Offset {
+				config.Offset = update.UpdateID + 1
+			}
+			handle(update, topics[i].thread())
+		}
+	}
+}
+
+// setTopic запоминает тему форума, в которую отвечать в чате
+func (b *Bot) setTopic(chatID int64, thread int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if thread == 0 {
+		delete(b.topics, chatID)
+		return
+	}
+	b.topics[chatID] = thread
+}
+
+// topic возвращает тему форума, в которой идет разговор с ботом, 0 - без темы
+func (b *Bot) topic(chatID int64) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.topics[chatID]
+}
+
+// addressedToBot проверяет, что команда в группе не адресована другому боту:
+// "/search@other_bot" пропускается, "/search" и "/search@наш_бот" - нет
+func (b *Bot) addressedToBot(message *tgbotapi.Message) bool {
+	command := message.CommandWithAt()
+	_, mention, found := strings.Cut(command, "@")
+	return !found || strings.EqualFold(mention, b.api.Self.UserName)
+}
+
+// isChatAllowed проверяет, можно ли пользователю работать с ботом в этом чате.
+// В личном чате решает ADMIN_USER_IDS, в группе достаточно, чтобы сама группа
+// была разрешена: TELEGRAM_CHAT_ID или GROUP_CHAT_IDS.
+func (b *Bot) isChatAllowed(chat *tgbotapi.Chat, userID int64) bool {
+	if b.isUserAllowed(userID) {
+		return true
+	}
+	if chat == nil || chat.IsPrivate() {
+		return false
+	}
+	if matchesChat(b.config.TelegramChatID, chat) {
+		return true
+	}
+	for _, allowed := range b.config.GroupChats {
+		if allowed == chat.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesChat сравнивает чат с идентификатором из настроек: "-1001234567890" или "@channel"
+func matchesChat(value string, chat *tgbotapi.Chat) bool {
+	value = strings.TrimSpace(value)
+	if username, found := strings.CutPrefix(value, "@"); found {
+		return username != "" && strings.EqualFold(username, chat.UserName)
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && id == chat.ID
+}
+
+// canManageChat проверяет право менять настройки чата: подписки, расписания,
+// уведомления. В личном чате оно есть у владельца, в группе - у ее
+// администраторов и у администраторов бота из ADMIN_USER_IDS.
+func (b *Bot) canManageChat(message *tgbotapi.Message) bool {
+	chat := message.Chat
+	if chat == nil || chat.IsPrivate() {
+		return true
+	}
+	// Анонимный администратор пишет от имени самой группы
+	if message.SenderChat != nil && message.SenderChat.ID == chat.ID {
+		return true
+	}
+	if message.From == nil || message.From.ID == groupAnonymousBotID {
+		return false
+	}
+	if len(b.config.AdminUsers) > 0 && b.isUserAllowed(message.From.ID) {
+		return true
+	}
+	return b.isChatAdmin(chat.ID, message.From.ID)
+}
+
+// isChatAdmin спрашивает у Telegram, администратор ли пользователь группы.
+// Ответ кэшируется на chatAdminTTL.
+func (b *Bot) isChatAdmin(chatID, userID int64) bool {
+	key := chatUserKey{chatID: chatID, userID: userID}
+
+	b.mu.Lock()
+	status, cached := b.chatAdmins[key]
+	b.mu.Unlock()
+	if cached && time.Since(status.checkedAt) < chatAdminTTL {
+		return status.admin
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("Не удалось проверить права %d в чате %d: %v", userID, chatID, err)
+		return false
+	}
+
+	status = chatAdminStatus{admin: member.IsCreator() || member.IsAdministrator(), checkedAt: time.Now()}
+	b.mu.Lock()
+	b.chatAdmins[key] = status
+	b.mu.Unlock()
+	return status.admin
+}
+
+// keyboardOwner возвращает пользователя, чьему выбору города или мастеру поиска
+// принадлежит сообщение с кнопками
+func (b *Bot) keyboardOwner(chatID int64, messageID int) (int64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, pending := range b.pendingChoices {
+		if key.chatID == chatID && pending.messageID == messageID {
+			return key.userID, true
+		}
+	}
+	for key, wizard := range b.wizards {
+		if key.chatID == chatID && wizard.messageID == messageID {
+			return key.userID, true
+		}
+	}
+	return 0, false
+}
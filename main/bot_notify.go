@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isOffValue - значение, выключающее настройку
+func isOffValue(value string) bool {
+	switch strings.ToLower(value) {
+	case "off", "выкл", "нет", "-":
+		return true
+	}
+	return false
+}
+
+// handleNotifyList показывает настройки уведомлений чата
+func (b *Bot) handleNotifyList(message *tgbotapi.Message, args []string) {
+	settings := b.subscriptions.Settings(message.Chat.ID)
+
+	quiet := "нет"
+	if !settings.QuietHours.Empty() {
+		quiet = settings.QuietHours.String()
+	}
+	digest := "нет, уведомления приходят сразу"
+	if settings.Digest != "" {
+		digest = DescribeSchedule(settings.Digest)
+	}
+
+	thresholds := b.config.AlertThresholds
+	var urgent []string
+	if thresholds.UrgentAllTimeLow {
+		urgent = append(urgent, "новый исторический минимум")
+	}
+	if thresholds.UrgentDropPercent > 0 {
+		urgent = append(urgent, fmt.Sprintf("снижение от %d%%", thresholds.UrgentDropPercent))
+	}
+	if len(urgent) == 0 {
+		urgent = append(urgent, "нет")
+	}
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf(`🔔 <b>Уведомления</b>
+
+🌙 Тихие часы: <b>%s</b> (%s)
+📬 Дайджест: <b>%s</b>
+🚨 Срочные, приходят сразу и со звуком: %s
+📨 Каналы: %s, по умолчанию <b>%s</b>
+
+💡 <code>/notify quiet 23:00-08:00</code>, <code>/notify digest daily 09:00</code>, <code>off</code> выключает
+💡 <code>/notify sinks all telegram email</code> - каналы подписок`,
+		html.EscapeString(quiet),
+		html.EscapeString(b.chatTimezoneName(message.Chat.ID)),
+		html.EscapeString(digest),
+		strings.Join(urgent, ", "),
+		html.EscapeString(strings.Join(b.enabledSinks(), ", ")),
+		html.EscapeString(strings.Join(b.config.DefaultSinks, ", ")),
+	))
+}
+
+// handleNotifyQuiet задает тихие часы: /notify quiet ЧЧ:ММ-ЧЧ:ММ|off
+func (b *Bot) handleNotifyQuiet(message *tgbotapi.Message, args []string) {
+	var window TimeWindow
+	if !isOffValue(args[0]) {
+		parsed, err := ParseTimeWindow(args[0])
+		if err != nil {
+			b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+			return
+		}
+		window = parsed
+	}
+
+	b.updateNotifySettings(message, func(settings *ChatSettings) {
+		settings.QuietHours = window
+	})
+}
+
+// handleNotifyDigest включает дайджест: /notify digest daily [ЧЧ:ММ]|weekly [день] [ЧЧ:ММ]|off
+func (b *Bot) handleNotifyDigest(message *tgbotapi.Message, args []string) {
+	var spec string
+	if !isOffValue(args[0]) {
+		parsed, err := ParseSchedule(strings.Join(args, " "))
+		if err != nil {
+			b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+			return
+		}
+		spec = parsed
+	}
+
+	b.updateNotifySettings(message, func(settings *ChatSettings) {
+		settings.Digest = spec
+	})
+}
+
+func (b *Bot) updateNotifySettings(message *tgbotapi.Message, update func(settings *ChatSettings)) {
+	if err := b.subscriptions.UpdateSettings(message.Chat.ID, update); err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось сохранить настройки:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+	b.handleNotifyList(message, nil)
+}
+
+// handleNotifySinks выбирает каналы уведомлений подписки:
+// /notify sinks номер|all канал [канал...]|default
+func (b *Bot) handleNotifySinks(message *tgbotapi.Message, args []string) {
+	id, ok := b.parseSubscriptionTarget(message, args[0])
+	if !ok {
+		return
+	}
+
+	var sinks []string
+	if !(len(args) == 2 && (strings.EqualFold(args[1], "default") || strings.EqualFold(args[1], "reset"))) {
+		enabled := b.enabledSinks()
+		for _, arg := range args[1:] {
+			for _, name := range strings.FieldsFunc(strings.ToLower(arg), isListSeparator) {
+				if !slices.Contains(enabled, name) {
+					b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Канал <code>%s</code> не включен, доступны: %s",
+						html.EscapeString(name), html.EscapeString(strings.Join(enabled, ", "))))
+					return
+				}
+				if !slices.Contains(sinks, name) {
+					sinks = append(sinks, name)
+				}
+			}
+		}
+	}
+
+	changed, err := b.subscriptions.SetSinks(message.Chat.ID, id, sinks)
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось сохранить каналы:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+	if changed == 0 {
+		if id == 0 {
+			b.replyHTML(message.Chat.ID, "❌ В чате нет подписок")
+		} else {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Подписка #%d не найдена", id))
+		}
+		return
+	}
+
+	b.handleWatches(message, nil)
+}
+
+// enabledSinks - включенные в NOTIFIERS каналы уведомлений
+func (b *Bot) enabledSinks() []string {
+	var sinks []string
+	for _, name := range b.config.Notifiers {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			sinks = append(sinks, name)
+		}
+	}
+	return sinks
+}
+
+// sinksText - каналы уведомлений подписки
+func (b *Bot) sinksText(sub Subscription) string {
+	if len(sub.Sinks) == 0 {
+		return strings.Join(b.config.DefaultSinks, ", ") + " (по умолчанию)"
+	}
+	return strings.Join(sub.Sinks, ", ")
+}
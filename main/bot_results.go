@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// resultViewTTL - сколько хранится результат поиска для листания и сортировки
+const resultViewTTL = 24 * time.Hour
+
+// maxResultViews - сколько результатов хранится одновременно, старые вытесняются
+const maxResultViews = 200
+
+type resultViewKey struct {
+	chatID    int64
+	messageID int
+}
+
+// resultView - отправленный результат поиска: страница и порядок, которые
+// сейчас показаны в сообщении. Кнопки под сообщением меняют их и
+// перерисовывают то же сообщение.
+type resultView struct {
+	result    *SearchResult
+	order     ResultOrder
+	page      int
+	createdAt time.Time
+}
+
+// sendResult отправляет первую страницу результата с кнопками листания и сортировки
+func (b *Bot) sendResult(chatID int64, result *SearchResult) {
+	view := &resultView{
+		result:    result,
+		order:     OrderByPrice,
+		createdAt: time.Now(),
+	}
+	pages := FormatResultPages(result, view.order)
+
+	msg := tgbotapi.NewMessage(chatID, pages[0])
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	if result.Empty() {
+		b.send(msg)
+		return
+	}
+	msg.ReplyMarkup = resultKeyboard(view, len(pages))
+
+	sent, err := b.send(msg)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneResultViews(time.Now())
+	b.resultViews[resultViewKey{chatID: chatID, messageID: sent.MessageID}] = view
+}
+
+// pruneResultViews удаляет устаревшие результаты и самые старые сверх maxResultViews.
+// Вызывается под b.mu.
+func (b *Bot) pruneResultViews(now time.Time) {
+	var oldestKey resultViewKey
+	var oldest time.Time
+	for key, view := range b.resultViews {
+		if now.Sub(view.createdAt) > resultViewTTL {
+			delete(b.resultViews, key)
+			continue
+		}
+		if oldest.IsZero() || view.createdAt.Before(oldest) {
+			oldestKey, oldest = key, view.createdAt
+		}
+	}
+	if len(b.resultViews) >= maxResultViews {
+		delete(b.resultViews, oldestKey)
+	}
+}
+
+// handleResultCallback листает и пересортировывает результат: res:page:<номер>, res:sort:<порядок>
+func (b *Bot) handleResultCallback(message *tgbotapi.Message, data string) {
+	key := resultViewKey{chatID: message.Chat.ID, messageID: message.MessageID}
+
+	b.mu.Lock()
+	view := b.resultViews[key]
+	b.mu.Unlock()
+
+	if view == nil {
+		b.api.Request(tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+		b.replyHTML(message.Chat.ID, "⌛ Результат поиска устарел, повторите поиск: /search")
+		return
+	}
+
+	page, order := view.page, view.order
+	action, value, _ := strings.Cut(data, ":")
+	switch action {
+	case "page":
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		page = number
+	case "sort":
+		if order != ResultOrder(value) {
+			order = ResultOrder(value)
+			page = 0
+		}
+	}
+
+	pages := FormatResultPages(view.result, order)
+	page = max(0, min(page, len(pages)-1))
+
+	// Telegram отвечает ошибкой на правку без изменений, например при нажатии
+	// на номер страницы или на уже выбранную сортировку
+	if page == view.page && order == view.order {
+		return
+	}
+	view.page, view.order = page, order
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(message.Chat.ID, message.MessageID, pages[page], resultKeyboard(view, len(pages)))
+	edit.ParseMode = "HTML"
+	edit.DisableWebPagePreview = true
+	b.send(edit)
+}
+
+// resultKeyboard - кнопки листания (если страниц больше одной) и сортировки
+func resultKeyboard(view *resultView, pages int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	if pages > 1 {
+		var navigation []tgbotapi.InlineKeyboardButton
+		if view.page > 0 {
+			navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("◀️", fmt.Sprintf("res:page:%d", view.page-1)))
+		}
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d / %d", view.page+1, pages), fmt.Sprintf("res:page:%d", view.page)))
+		if view.page < pages-1 {
+			navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("▶️", fmt.Sprintf("res:page:%d", view.page+1)))
+		}
+		rows = append(rows, navigation)
+	}
+
+	var sorting []tgbotapi.InlineKeyboardButton
+	for _, option := range resultOrders {
+		label := option.Label
+		if option.Order == view.order {
+			label = "✓ " + label
+		}
+		sorting = append(sorting, tgbotapi.NewInlineKeyboardButtonData(label, "res:sort:"+string(option.Order)))
+	}
+	rows = append(rows, sorting)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// resolveCities находит коды всех городов из аргументов. Если какой-то город
+// не найден или требует уточнения, возвращает false; после выбора города
+// команда handler повторяется целиком.
+func (b *Bot) resolveCities(message *tgbotapi.Message, args []string, handler commandHandler) ([]string, bool) {
+	codes := make([]string, 0, len(args))
+	for i := range args {
+		code, ok := b.resolveArg(message, args, i, handler)
+		if !ok {
+			return nil, false
+		}
+		codes = append(codes, code)
+	}
+	return codes, true
+}
+
+// handleOriginSet заменяет города вылета по умолчанию: /origin set город [город...]
+func (b *Bot) handleOriginSet(message *tgbotapi.Message, args []string) {
+	codes, ok := b.resolveCities(message, args, (*Bot).handleOriginSet)
+	if !ok {
+		return
+	}
+
+	old := b.flightSearch.DefaultQuery().Origins
+	b.flightSearch.SetOriginIATA(codes...)
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf("✅ <b>Города вылета изменены:</b>\n%s\n➡️\n%s",
+		formatOriginsHTML(old), formatOriginsHTML(b.flightSearch.DefaultQuery().Origins)))
+}
+
+// handleOriginAdd добавляет города вылета по умолчанию: /origin add город [город...]
+func (b *Bot) handleOriginAdd(message *tgbotapi.Message, args []string) {
+	codes, ok := b.resolveCities(message, args, (*Bot).handleOriginAdd)
+	if !ok {
+		return
+	}
+
+	var skipped []string
+	for _, code := range codes {
+		if !b.flightSearch.AddOriginIATA(code) {
+			skipped = append(skipped, code)
+		}
+	}
+
+	text := "✅ <b>Города вылета:</b>\n" + formatOriginsHTML(b.flightSearch.DefaultQuery().Origins)
+	if len(skipped) > 0 {
+		text += fmt.Sprintf("\n\nℹ️ Уже были в списке: %s", strings.Join(skipped, ", "))
+	}
+	b.replyHTML(message.Chat.ID, text)
+}
+
+// handleOriginRemove убирает города вылета по умолчанию: /origin remove город [город...]
+func (b *Bot) handleOriginRemove(message *tgbotapi.Message, args []string) {
+	for i, arg := range args {
+		origins := b.flightSearch.DefaultQuery().Origins
+
+		// Код из списка убираем как есть, без поиска по справочнику
+		origin := strings.ToUpper(arg)
+		if !containsString(origins, origin) {
+			code, ok := b.resolveArg(message, args[i:], 0, (*Bot).handleOriginRemove)
+			if !ok {
+				return
+			}
+			origin = findOrigin(origins, code)
+		}
+
+		if err := b.flightSearch.RemoveOriginIATA(origin); err != nil {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ %s\n\nГорода вылета:\n%s",
+				html.EscapeString(err.Error()), formatOriginsHTML(origins)))
+			return
+		}
+	}
+
+	b.replyHTML(message.Chat.ID, "🗑 <b>Города вылета:</b>\n"+formatOriginsHTML(b.flightSearch.DefaultQuery().Origins))
+}
+
+// findOrigin возвращает город вылета из списка, относящийся к тому же городу, что и code:
+// в списке может быть аэропорт (VKO), а пользователь указал город (москва)
+func findOrigin(origins []string, code string) string {
+	for _, origin := range origins {
+		if airports.CityCode(origin) == airports.CityCode(code) {
+			return origin
+		}
+	}
+	return code
+}
+
+// handleOriginList показывает города вылета по умолчанию
+func (b *Bot) handleOriginList(message *tgbotapi.Message, args []string) {
+	b.replyHTML(message.Chat.ID, "🛫 <b>Города вылета по умолчанию:</b>\n"+
+		formatOriginsHTML(b.flightSearch.DefaultQuery().Origins)+
+		"\n\n💡 <code>/origin add город</code>, <code>/origin remove город</code>, <code>/origin set город [город...]</code>")
+}
+
+func formatOriginsHTML(origins []string) string {
+	lines := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		line := fmt.Sprintf("• %s - %s", origin, getMetroName(origin))
+		if airports.HasSeveralAirports(origin) && airports.CityCode(origin) == origin {
+			line += fmt.Sprintf(" (%s)", strings.Join(airports.ExpandCode(origin), ", "))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleDestSet меняет направление по умолчанию: /dest set город
+func (b *Bot) handleDestSet(message *tgbotapi.Message, args []string) {
+	// Название может состоять из нескольких слов: "/dest set koh samui"
+	cityName := strings.Join(args, " ")
+	destination, ok := b.resolveCity(message, cityName, func(code string) {
+		b.handleDestSet(message, []string{code})
+	})
+	if !ok {
+		return
+	}
+
+	old := b.flightSearch.DefaultQuery().Destination
+	b.flightSearch.SetDestination(destination)
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf("✅ <b>Направление по умолчанию изменено:</b>\n%s → %s",
+		getMetroName(old), getMetroName(destination)))
+}
+
+// handleDestList показывает направление по умолчанию и направления подписок чата
+func (b *Bot) handleDestList(message *tgbotapi.Message, args []string) {
+	query := b.flightSearch.DefaultQuery()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🎯 <b>Направление по умолчанию:</b>\n• %s - %s\n",
+		query.Destination, getMetroName(query.Destination)))
+
+	seen := map[string]bool{query.Destination: true}
+	var watched []string
+	for _, sub := range b.subscriptions.ForChat(message.Chat.ID) {
+		if seen[sub.Destination] {
+			continue
+		}
+		seen[sub.Destination] = true
+		watched = append(watched, fmt.Sprintf("• %s - %s", sub.Destination, getMetroName(sub.Destination)))
+	}
+	if len(watched) > 0 {
+		sb.WriteString("\n👀 <b>Направления ваших подписок:</b>\n")
+		sb.WriteString(strings.Join(watched, "\n"))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n💡 <code>/dest set город</code> - сменить направление")
+	b.replyHTML(message.Chat.ID, sb.String())
+}
+
+// handleCities показывает города справочника, всех или одной страны: /cities [страна]
+func (b *Bot) handleCities(message *tgbotapi.Message, args []string) {
+	country := strings.Join(args, " ")
+
+	list := GetCityList(country)
+	if list == "" {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Страна '%s' не найдена. Полный список: /cities",
+			html.EscapeString(country)))
+		return
+	}
+
+	text := "🏙️ <b>Доступные города для поиска:</b>\n\n" + list
+	text += "\n\n💡 <i>Используйте команду /search ГОРОД для поиска</i>\n"
+	text += "Например:\n"
+	text += "<code>/search бангкок</code> - поиск по названию\n"
+	text += "<code>/search BKK</code> - поиск по коду аэропорта\n"
+	text += "<code>/cities таиланд</code> - города одной страны"
+	b.replyHTML(message.Chat.ID, text)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleScheduleList показывает расписания подписок чата и ближайшие проверки
+func (b *Bot) handleScheduleList(message *tgbotapi.Message, args []string) {
+	spec, _ := b.scheduler.Defaults()
+
+	var sb strings.Builder
+	sb.WriteString("🕙 <b>Расписание проверок подписок</b>\n")
+	sb.WriteString(fmt.Sprintf("Часовой пояс: <b>%s</b>\n", html.EscapeString(b.chatTimezoneName(message.Chat.ID))))
+	sb.WriteString(fmt.Sprintf("По умолчанию: %s\n\n", html.EscapeString(DescribeSchedule(spec))))
+
+	subs := b.subscriptions.ForChat(message.Chat.ID)
+	if len(subs) == 0 {
+		sb.WriteString("• нет подписок, добавьте маршрут командой <code>/watch город</code>\n")
+	}
+	for _, sub := range subs {
+		sb.WriteString(fmt.Sprintf("• <b>#%d</b> %s → %s: %s\n",
+			sub.ID, strings.Join(sub.Origins, "/"), getCityName(sub.Destination), b.scheduleHTML(sub)))
+	}
+
+	sb.WriteString("\n💡 <code>/schedule set 3 daily 08:30</code>, <code>/schedule set all weekly пт 18:00</code>, " +
+		"<code>/schedule tz Asia/Novosibirsk</code>. Подробнее: <code>/help schedule</code>")
+	b.replyHTML(message.Chat.ID, sb.String())
+}
+
+// handleScheduleSet меняет расписание: /schedule set номер|all расписание
+func (b *Bot) handleScheduleSet(message *tgbotapi.Message, args []string) {
+	id, ok := b.parseSubscriptionTarget(message, args[0])
+	if !ok {
+		return
+	}
+
+	spec, err := ParseSchedule(strings.Join(args[1:], " "))
+	if err != nil {
+		b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+		return
+	}
+	b.updateSchedule(message, id, spec)
+}
+
+// handleScheduleReset возвращает расписание по умолчанию: /schedule reset номер|all
+func (b *Bot) handleScheduleReset(message *tgbotapi.Message, args []string) {
+	id, ok := b.parseSubscriptionTarget(message, args[0])
+	if !ok {
+		return
+	}
+	b.updateSchedule(message, id, "")
+}
+
+func (b *Bot) updateSchedule(message *tgbotapi.Message, id int, spec string) {
+	changed, err := b.subscriptions.SetSchedule(message.Chat.ID, id, spec)
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось сохранить расписание:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+	if changed == 0 {
+		if id == 0 {
+			b.replyHTML(message.Chat.ID, "❌ В чате нет подписок")
+		} else {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Подписка #%d не найдена", id))
+		}
+		return
+	}
+
+	b.handleScheduleList(message, nil)
+}
+
+// parseSubscriptionTarget разбирает номер подписки; "all" означает все подписки чата (0)
+func (b *Bot) parseSubscriptionTarget(message *tgbotapi.Message, arg string) (int, bool) {
+	if strings.EqualFold(arg, "all") || strings.EqualFold(arg, "все") {
+		return 0, true
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || id <= 0 {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Некорректный номер подписки: <code>%s</code>\nСписок подписок: /watches",
+			html.EscapeString(arg)))
+		return 0, false
+	}
+	return id, true
+}
+
+// handleScheduleTimezone меняет часовой пояс расписаний чата: /schedule tz зона|reset
+func (b *Bot) handleScheduleTimezone(message *tgbotapi.Message, args []string) {
+	var timezone string
+	if !strings.EqualFold(args[0], "reset") {
+		value, err := ParseTimezone(args[0])
+		if err != nil {
+			b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+			return
+		}
+		timezone = value
+	}
+
+	err := b.subscriptions.UpdateSettings(message.Chat.ID, func(settings *ChatSettings) {
+		settings.Timezone = timezone
+	})
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось сохранить часовой пояс:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+
+	b.handleScheduleList(message, nil)
+}
+
+// scheduleHTML описывает расписание подписки и время следующей проверки
+func (b *Bot) scheduleHTML(sub Subscription) string {
+	spec, _ := b.scheduler.Schedule(sub)
+	text := html.EscapeString(DescribeSchedule(spec))
+	if next, ok := b.scheduler.Next(sub); ok {
+		text += ", следующая " + next.In(b.scheduler.Location(sub.ChatID)).Format("02.01 15:04")
+	}
+	return text
+}
+
+func (b *Bot) chatTimezoneName(chatID int64) string {
+	_, timezone := b.scheduler.Schedule(Subscription{ChatID: chatID})
+	if timezone == "" {
+		return "время сервера, " + time.Local.String()
+	}
+	return timezone
+}
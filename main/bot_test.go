@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/robfig/cron/v3"
+)
+
+// fakeTelegram - заглушка Bot API: отвечает на getMe и запоминает отправленные сообщения
+type fakeTelegram struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	messages  []map[string]string
+	callbacks []string        // Тексты ответов на нажатия кнопок
+	admins    map[string]bool // "chat:user" -> администратор группы
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	fake := &fakeTelegram{admins: make(map[string]bool)}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeTelegram) handle(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	switch method {
+	case "getMe":
+		fmt.Fprint(w, `{"ok":true,"result":{"id":100,"is_bot":true,"first_name":"Bot","username":"fare_bot"}}`)
+	case "getChatMember":
+		f.mu.Lock()
+		admin := f.admins[r.Form.Get("chat_id")+":"+r.Form.Get("user_id")]
+		f.mu.Unlock()
+		status := "member"
+		if admin {
+			status = "administrator"
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":{"user":{"id":%s},"status":"%s"}}`, r.Form.Get("user_id"), status)
+	case "sendMessage":
+		params := make(map[string]string)
+		for key := range r.Form {
+			params[key] = r.Form.Get(key)
+		}
+		f.mu.Lock()
+		f.messages = append(f.messages, params)
+		messageID := len(f.messages)
+		f.mu.Unlock()
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d,"chat":{"id":%s,"type":"private"}}}`, messageID, params["chat_id"])
+	case "answerCallbackQuery":
+		f.mu.Lock()
+		f.callbacks = append(f.callbacks, r.Form.Get("text"))
+		f.mu.Unlock()
+		fmt.Fprint(w, `{"ok":true,"result":true}`)
+	default:
+		fmt.Fprint(w, `{"ok":true,"result":true}`)
+	}
+}
+
+// Messages возвращает отправленные сообщения
+func (f *fakeTelegram) Messages() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]map[string]string(nil), f.messages...)
+}
+
+// Callbacks возвращает тексты ответов на нажатия кнопок
+func (f *fakeTelegram) Callbacks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.callbacks...)
+}
+
+// newTestBot создает бота, подписки и планировщик поверх заглушки Telegram
+// и поставщиков цен; файлы хранятся во временном каталоге теста
+func newTestBot(t *testing.T, config *AppConfig, providers ...FareProvider) (*Bot, *fakeTelegram) {
+	t.Helper()
+
+	telegram := newFakeTelegram(t)
+	dir := t.TempDir()
+
+	config.TelegramBotToken = "test"
+	config.TelegramBotUrl = telegram.server.URL
+	if config.Schedule == "" {
+		config.Schedule = defaultSchedule
+	}
+	if config.OriginIATA == nil {
+		config.OriginIATA = []string{"OVB"}
+	}
+	if config.MonthsToSearch == 0 {
+		config.MonthsToSearch = 2
+	}
+	config.SearchWorkers = 4
+	config.HistoryPath = filepath.Join(dir, "history.jsonl")
+	config.SubscriptionsPath = filepath.Join(dir, "subscriptions.json")
+	config.OutboxPath = filepath.Join(dir, "outbox.json")
+	config.LedgerPath = filepath.Join(dir, "notified.json")
+
+	history, err := NewPriceHistory(config.HistoryPath, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions, err := NewSubscriptionStore(config.SubscriptionsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flightSearch := NewFlightSearch(config, providers, history)
+	bot, err := NewBot(config, flightSearch, subscriptions, NewScheduler(config, subscriptions))
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+	return bot, telegram
+}
+
+func testMessage(chatID, userID int64, chatType string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		Chat: &tgbotapi.Chat{ID: chatID, Type: chatType},
+		From: &tgbotapi.User{ID: userID},
+	}
+}
+
+// TestHandlersAndSchedulerRunConcurrently запускает команды, меняющие настройки
+// поиска и подписки, параллельно с поиском по расписанию. Смысл теста - в запуске
+// с -race: гонки данных между ботом и планировщиком детектор покажет как ошибку.
+func TestHandlersAndSchedulerRunConcurrently(t *testing.T) {
+	var price atomic.Int64
+	price.Store(20000)
+	provider := &fakeProvider{name: "fake", fares: func(query FareQuery) ([]Flight, error) {
+		// Цена падает с каждым запросом, чтобы по подпискам шли уведомления
+		return []Flight{fakeFlight(query, int(price.Add(-50)), "S7")}, nil
+	}}
+
+	config := &AppConfig{
+		DestinationIATA: "AER",
+		Notifiers:       []string{"telegram"},
+		DefaultSinks:    []string{"telegram"},
+		NotifyRetry:     RetryPolicy{MaxAttempts: 1},
+		Ledger:          LedgerPolicy{Window: time.Hour},
+		AlertThresholds: AlertThresholds{MinDropPercent: 1, NotifyAllTimeLow: true},
+	}
+	bot, telegram := newTestBot(t, config, provider)
+
+	notifiers, err := newNotifiers(config, bot.api)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := NewOutbox(config.OutboxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := NewNotificationLedger(config.LedgerPath, config.Ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := NewAlertDelivery(config, notifiers, bot.subscriptions, bot.scheduler, outbox, ledger)
+
+	if _, err := bot.subscriptions.Add(Subscription{ChatID: 1, Origins: []string{"OVB"}, Destination: "AER"}); err != nil {
+		t.Fatal(err)
+	}
+	startScheduledSearch(delivery, bot.scheduler, config, bot.flightSearch, bot.flightSearch.history)
+	t.Cleanup(bot.scheduler.Stop)
+
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	// Планировщик: выполняются задания, зарегистрированные в cron
+	run(func(i int) {
+		bot.scheduler.mu.Lock()
+		ids := make([]cron.EntryID, 0, len(bot.scheduler.entries))
+		for _, id := range bot.scheduler.entries {
+			ids = append(ids, id)
+		}
+		bot.scheduler.mu.Unlock()
+
+		for _, id := range ids {
+			// Задание могло быть удалено после смены расписания
+			if job := bot.scheduler.cron.Entry(id).Job; job != nil {
+				job.Run()
+			}
+		}
+	})
+	run(func(i int) {
+		delivery.Flush(time.Now())
+	})
+	run(func(i int) {
+		origins := [][]string{{"OVB"}, {"OVB", "KJA"}}
+		bot.handleOriginSet(testMessage(1, 1, "private"), origins[i%2])
+	})
+	run(func(i int) {
+		destinations := []string{"AER", "DXB"}
+		bot.handleDestSet(testMessage(1, 1, "private"), []string{destinations[i%2]})
+	})
+	run(func(i int) {
+		bot.handleWatch(testMessage(int64(1+i%3), 1, "private"), []string{"AER", "30000"})
+	})
+	run(func(i int) {
+		bot.handleScheduleTimezone(testMessage(2, 1, "private"), []string{"Asia/Novosibirsk"})
+	})
+	wg.Wait()
+
+	if calls := len(provider.Calls()); calls == 0 {
+		t.Error("планировщик не выполнил ни одного поиска")
+	}
+	if len(bot.subscriptions.All()) != 11 {
+		t.Errorf("подписок %d, ожидалось 11", len(bot.subscriptions.All()))
+	}
+	if len(telegram.Messages()) == 0 {
+		t.Error("бот не отправил ни одного сообщения")
+	}
+}
+
+func TestHelpDescribesConfiguredSchedule(t *testing.T) {
+	bot, telegram := newTestBot(t, &AppConfig{DestinationIATA: "AER", Schedule: "0 8 * * 5", ScheduleTimezone: "Asia/Novosibirsk"})
+
+	bot.handleHelp(testMessage(1, 1, "private"), nil)
+
+	messages := telegram.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("отправлено сообщений: %d", len(messages))
+	}
+	text := messages[0]["text"]
+	want := "подписки " + DescribeSchedule("0 8 * * 5") + " (Asia/Novosibirsk)"
+	if !strings.Contains(text, want) || strings.Contains(text, "10:00") {
+		t.Errorf("справка не описывает расписание %q:\n%s", want, text)
+	}
+}
+
+// testCallback - нажатие пользователем userID кнопки под сообщением messageID
+func testCallback(chatID, userID int64, messageID int, data string) *tgbotapi.CallbackQuery {
+	message := testMessage(chatID, 0, "supergroup")
+	message.MessageID = messageID
+	return &tgbotapi.CallbackQuery{ID: "1", From: &tgbotapi.User{ID: userID}, Message: message, Data: data}
+}
+
+func TestWizardAndCityChoiceBelongToTheirUser(t *testing.T) {
+	const group = -100123
+	bot, telegram := newTestBot(t, &AppConfig{DestinationIATA: "AER", GroupChats: []int64{group}})
+
+	// Двое участников группы запускают мастер поиска, каждый - свой
+	bot.handleWizard(testMessage(group, 1, "supergroup"), nil)
+	bot.handleWizard(testMessage(group, 2, "supergroup"), nil)
+	first, second := bot.activeWizard(group, 1), bot.activeWizard(group, 2)
+	if first == nil || second == nil || first == second {
+		t.Fatal("у каждого участника должен быть свой мастер")
+	}
+
+	// Текст второго участника не попадает в мастер первого
+	answer := testMessage(group, 2, "supergroup")
+	answer.Text = "3"
+	bot.handleWizardText(answer)
+	if first.step != wizardOrigin {
+		t.Error("ответ второго участника изменил мастер первого")
+	}
+
+	// Кнопки мастера первого участника второму не подчиняются
+	bot.handleCallback(testCallback(group, 2, first.messageID, "wiz:next"))
+	if first.step != wizardOrigin {
+		t.Error("второй участник нажал кнопку в чужом мастере")
+	}
+	if callbacks := telegram.Callbacks(); len(callbacks) != 1 || callbacks[0] == "" {
+		t.Errorf("нажатие чужой кнопки должно получить ответ-предупреждение: %q", callbacks)
+	}
+	bot.handleCallback(testCallback(group, 1, first.messageID, "wiz:next"))
+	if first.step != wizardDestination {
+		t.Error("автор мастера не смог перейти к следующему шагу")
+	}
+
+	// Выбор города ждет ответа только от отправившего команду
+	var chosen []string
+	matches := []CityMatch{
+		{City: City{Code: "AER", NameRu: "Сочи"}, Codes: []string{"AER"}},
+		{City: City{Code: "KJA", NameRu: "Красноярск"}, Codes: []string{"KJA"}},
+	}
+	bot.askCityChoice(testMessage(group, 1, "supergroup"), "с", matches, func(code string) {
+		chosen = append(chosen, code)
+	})
+	choiceID := len(telegram.Messages())
+
+	bot.handleCallback(testCallback(group, 2, choiceID, "city:KJA"))
+	bot.handleCallback(testCallback(group, 1, choiceID, "city:AER"))
+	if fmt.Sprint(chosen) != "[AER]" {
+		t.Errorf("выбран город %v, ожидался выбор автора команды [AER]", chosen)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleWatch добавляет подписку: /watch город [цена] [месяцев] [даты]
+func (b *Bot) handleWatch(message *tgbotapi.Message, args []string) {
+	destination, ok := b.resolveArg(message, args, 0, (*Bot).handleWatch)
+	if !ok {
+		return
+	}
+
+	sub := Subscription{
+		ChatID:      message.Chat.ID,
+		ThreadID:    b.topic(message.Chat.ID),
+		Origins:     b.flightSearch.DefaultQuery().Origins,
+		Destination: destination,
+	}
+
+	// Первое число - максимальная цена, второе - глубина поиска в месяцах,
+	// диапазон "7-14" - число ночей для поездки туда-обратно,
+	// остальные аргументы - фильтр дат
+	numbers := 0
+	for _, arg := range args[1:] {
+		if value, err := strconv.Atoi(arg); err == nil {
+			switch numbers {
+			case 0:
+				if value <= 0 {
+					b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Некорректная цена: <code>%d</code>, нужна сумма больше нуля", value))
+					return
+				}
+				sub.MaxPrice = value
+			case 1:
+				months, err := ParseMonths(arg)
+				if err != nil {
+					b.replyHTML(message.Chat.ID, "❌ "+html.EscapeString(err.Error()))
+					return
+				}
+				sub.MonthsToSearch = months
+			default:
+				b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Лишний числовой аргумент: <code>%d</code>", value))
+				return
+			}
+			numbers++
+			continue
+		}
+
+		// Диапазон ночей вида "7-14" включает режим туда-обратно
+		if minNights, maxNights, err := ParseNightsRange(arg); err == nil {
+			sub.RoundTrip = true
+			sub.MinNights, sub.MaxNights = minNights, maxNights
+			continue
+		}
+
+		filter, err := ParseDateFilter(arg)
+		if err != nil {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		sub.DateFilter = filter
+	}
+
+	saved, err := b.subscriptions.Add(sub)
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось сохранить подписку:</b>\n<code>%v</code>", err))
+		return
+	}
+
+	b.replyHTML(message.Chat.ID, "✅ <b>Подписка добавлена:</b>\n"+b.formatSubscriptionHTML(saved))
+}
+
+// handleUnwatch удаляет подписку: /unwatch номер
+func (b *Bot) handleUnwatch(message *tgbotapi.Message, args []string) {
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Некорректный номер подписки: <code>%s</code>\nСписок подписок: /watches",
+			html.EscapeString(args[0])))
+		return
+	}
+
+	removed, err := b.subscriptions.Remove(message.Chat.ID, id)
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Не удалось удалить подписку:</b>\n<code>%v</code>", err))
+		return
+	}
+	if !removed {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Подписка #%d не найдена", id))
+		return
+	}
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf("🗑 Подписка #%d удалена", id))
+}
+
+// handleWatches показывает подписки чата
+func (b *Bot) handleWatches(message *tgbotapi.Message, args []string) {
+	b.replyHTML(message.Chat.ID, "📋 <b>Ваши подписки:</b>\n"+b.formatSubscriptionsHTML(message.Chat.ID))
+}
+
+func (b *Bot) formatSubscriptionsHTML(chatID int64) string {
+	subs := b.subscriptions.ForChat(chatID)
+	if len(subs) == 0 {
+		return "• нет подписок, добавьте маршрут командой <code>/watch город</code>"
+	}
+
+	lines := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		lines = append(lines, b.formatSubscriptionHTML(sub))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (b *Bot) formatSubscriptionHTML(sub Subscription) string {
+	query := sub.Query(b.flightSearch.DefaultQuery(), b.subscriptions.Settings(sub.ChatID))
+
+	route := "→"
+	if query.RoundTrip {
+		route = fmt.Sprintf("⇄ (%d–%d ноч.)", query.MinNights, query.MaxNights)
+	}
+
+	return fmt.Sprintf("• <b>#%d</b> %s %s %s, до %d₽, %d мес., %s\n  🕙 %s\n  📨 %s",
+		sub.ID,
+		strings.Join(query.Origins, "/"),
+		route,
+		getCityName(query.Destination),
+		query.MaxPrice,
+		query.MonthsToSearch,
+		query.DateFilter.String(),
+		b.scheduleHTML(sub),
+		html.EscapeString(b.sinksText(sub)),
+	)
+}
+
+// replyHTML отвечает в чат; длинный текст делится на несколько сообщений
+func (b *Bot) replyHTML(chatID int64, text string) {
+	for _, part := range SplitMessageHTML(text) {
+		msg := tgbotapi.NewMessage(chatID, part)
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		b.send(msg)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleWeekend ищет поездки на выходные: /weekend [город...] [выходных]
+func (b *Bot) handleWeekend(message *tgbotapi.Message, args []string) {
+	weekends := b.config.WeekendsToSearch
+
+	// Число в конце - сколько ближайших выходных проверить
+	if len(args) > 0 {
+		if count, err := strconv.Atoi(args[len(args)-1]); err == nil {
+			if count < 1 || count > maxWeekends {
+				b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Число выходных должно быть от 1 до %d", maxWeekends))
+				return
+			}
+			weekends = count
+			args = args[:len(args)-1]
+		}
+	}
+
+	query := b.chatQuery(message.Chat.ID)
+	destinations := []string{query.Destination}
+	if len(args) > 0 {
+		codes, ok := b.resolveCities(message, args, func(b *Bot, message *tgbotapi.Message, cities []string) {
+			b.handleWeekend(message, append(cities, strconv.Itoa(weekends)))
+		})
+		if !ok {
+			return
+		}
+		destinations = codes
+	}
+
+	b.replyHTML(message.Chat.ID, fmt.Sprintf(
+		"🔍 <b>Ищу поездки на выходные...</b>\n%d выходных, направлений: %d\n<i>%s</i>",
+		weekends, len(destinations), html.EscapeString(b.config.WeekendRules.String())))
+
+	// Каждое направление - отдельный поиск, время ожидания растет с их числом
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(destinations))*searchTimeout)
+	defer cancel()
+
+	reports, err := b.flightSearch.SearchWeekends(ctx, query, destinations, weekends, b.config.WeekendRules)
+	if err != nil {
+		b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ <b>Ошибка при поиске:</b>\n<code>%s</code>", html.EscapeString(err.Error())))
+		return
+	}
+
+	b.replyHTML(message.Chat.ID, FormatWeekendsHTML(query, reports, b.config.WeekendRules))
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// wizardTTL - сколько мастер поиска ждет ответа, прежде чем закрыться
+const wizardTTL = 15 * time.Minute
+
+// wizardStep - шаг мастера поиска
+type wizardStep int
+
+const (
+	wizardOrigin wizardStep = iota
+	wizardDestination
+	wizardDates
+	wizardPrice
+	wizardDirect
+	wizardConfirm
+)
+
+// wizardPrices - варианты максимальной цены на кнопках
+var wizardPrices = []int{20000, 35000, 50000, 70000, 100000}
+
+// wizardMonths - варианты глубины поиска на кнопках
+var wizardMonths = []int{1, 2, 3, 6, 12}
+
+// searchWizard - состояние мастера поиска пользователя в одном чате. Мастер ведет одно
+// сообщение с кнопками и редактирует его на каждом шаге. Текстовый ответ
+// пользователя запоминается: если его отредактировать, значение применится заново.
+type searchWizard struct {
+	chatID    int64
+	userID    int64 // Кто запустил мастер: только он отвечает на шаги
+	messageID int
+	step      wizardStep
+	query     SearchQuery
+	notice    string // Подсказка или ошибка к текущему шагу
+
+	inputMessageID int        // Последний текстовый ответ пользователя
+	inputStep      wizardStep // Шаг, на который был дан этот ответ
+
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// handleWizard запускает мастер поиска: /wizard
+func (b *Bot) handleWizard(message *tgbotapi.Message, args []string) {
+	wizard := &searchWizard{
+		chatID: message.Chat.ID,
+		userID: message.From.ID,
+		step:   wizardOrigin,
+		query:  b.chatQuery(message.Chat.ID),
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, wizard.text())
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = b.wizardKeyboard(wizard)
+	sent, err := b.send(msg)
+	if err != nil {
+		return
+	}
+	wizard.messageID = sent.MessageID
+
+	b.mu.Lock()
+	previous := b.wizards[wizard.key()]
+	if previous != nil {
+		previous.timer.Stop()
+	}
+	wizard.expiresAt = time.Now().Add(wizardTTL)
+	wizard.timer = time.AfterFunc(wizardTTL, func() { b.expireWizard(wizard) })
+	b.wizards[wizard.key()] = wizard
+	b.mu.Unlock()
+
+	// У пользователя в чате работает только один мастер, прежний закрываем
+	if previous != nil {
+		b.closeWizardMessage(previous, "ℹ️ Мастер поиска перезапущен.")
+	}
+}
+
+func (w *searchWizard) key() chatUserKey {
+	return chatUserKey{chatID: w.chatID, userID: w.userID}
+}
+
+// activeWizard возвращает мастер пользователя в чате и продлевает его время жизни
+func (b *Bot) activeWizard(chatID, userID int64) *searchWizard {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wizard := b.wizards[chatUserKey{chatID: chatID, userID: userID}]
+	if wizard == nil {
+		return nil
+	}
+	wizard.expiresAt = time.Now().Add(wizardTTL)
+	wizard.timer.Reset(wizardTTL)
+	return wizard
+}
+
+// finishWizard убирает мастер, если он еще активен
+func (b *Bot) finishWizard(wizard *searchWizard) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wizards[wizard.key()] != wizard {
+		return false
+	}
+	wizard.timer.Stop()
+	delete(b.wizards, wizard.key())
+	return true
+}
+
+// expireWizard закрывает мастер, на который долго не отвечали
+func (b *Bot) expireWizard(wizard *searchWizard) {
+	b.mu.Lock()
+	expired := b.wizards[wizard.key()] == wizard && !time.Now().Before(wizard.expiresAt)
+	if expired {
+		delete(b.wizards, wizard.key())
+	}
+	b.mu.Unlock()
+
+	if expired {
+		b.closeWizardMessage(wizard, "⌛ Мастер поиска закрыт: время ожидания истекло. Начать заново: /wizard")
+	}
+}
+
+// closeWizardMessage заменяет сообщение мастера итоговым текстом без кнопок
+func (b *Bot) closeWizardMessage(wizard *searchWizard, text string) {
+	edit := tgbotapi.NewEditMessageText(wizard.chatID, wizard.messageID, text)
+	edit.ParseMode = "HTML"
+	b.send(edit)
+}
+
+// renderWizard обновляет сообщение мастера под текущий шаг
+func (b *Bot) renderWizard(wizard *searchWizard) {
+	edit := tgbotapi.NewEditMessageTextAndMarkup(wizard.chatID, wizard.messageID, wizard.text(), b.wizardKeyboard(wizard))
+	edit.ParseMode = "HTML"
+	b.send(edit)
+}
+
+// handleWizardCallback обрабатывает кнопки мастера пользователя userID: wiz:<действие>[:<значение>]
+func (b *Bot) handleWizardCallback(message *tgbotapi.Message, userID int64, data string) {
+	wizard := b.activeWizard(message.Chat.ID, userID)
+	if wizard == nil || wizard.messageID != message.MessageID {
+		b.api.Request(tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+		b.replyHTML(message.Chat.ID, "⌛ Этот мастер поиска уже закрыт. Начать заново: /wizard")
+		return
+	}
+
+	action, value, _ := strings.Cut(data, ":")
+	wizard.notice = ""
+
+	switch action {
+	case "cancel":
+		if b.finishWizard(wizard) {
+			b.closeWizardMessage(wizard, "✖️ Поиск отменен.")
+		}
+		return
+	case "back":
+		if wizard.step > wizardOrigin {
+			wizard.step--
+		}
+	case "next":
+		wizard.step++
+	case "go":
+		if !b.finishWizard(wizard) {
+			return
+		}
+		b.closeWizardMessage(wizard, "🧭 <b>Параметры поиска:</b>\n"+wizard.summary())
+		b.runSearch(wizard.chatID, wizard.query)
+		return
+	case "o":
+		if value == "*" {
+			wizard.query.Origins = b.flightSearch.DefaultQuery().Origins
+		} else {
+			wizard.query.Origins = []string{value}
+		}
+		wizard.step = wizardDestination
+	case "d":
+		wizard.query.Destination = value
+		wizard.step = wizardDates
+	case "m":
+		months, err := ParseMonths(value)
+		if err != nil {
+			return
+		}
+		wizard.query.MonthsToSearch = months
+		wizard.query.DateFilter = DateFilter{}
+		wizard.step = wizardPrice
+	case "p":
+		price, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		wizard.query.MaxPrice = price
+		wizard.step = wizardDirect
+	case "direct":
+		wizard.query.Filters.DirectOnly = value == "1"
+		wizard.step = wizardConfirm
+	}
+
+	b.renderWizard(wizard)
+}
+
+// handleWizardText принимает текстовый ответ на шаг мастера.
+// Возвращает false, если у автора сообщения нет активного мастера в этом чате.
+func (b *Bot) handleWizardText(message *tgbotapi.Message) bool {
+	wizard := b.activeWizard(message.Chat.ID, message.From.ID)
+	if wizard == nil {
+		return false
+	}
+
+	wizard.inputMessageID = message.MessageID
+	wizard.inputStep = wizard.step
+	b.applyWizardInput(wizard, message, wizard.step, message.Text)
+	return true
+}
+
+// handleEditedMessage применяет исправленный текстовый ответ мастеру поиска
+func (b *Bot) handleEditedMessage(message *tgbotapi.Message) {
+	wizard := b.activeWizard(message.Chat.ID, message.From.ID)
+	if wizard == nil || wizard.inputMessageID != message.MessageID {
+		return
+	}
+	b.applyWizardInput(wizard, message, wizard.inputStep, message.Text)
+}
+
+// applyWizardInput записывает текстовый ответ в шаг step. Если это текущий шаг,
+// мастер переходит дальше; исправление более раннего шага только обновляет сводку.
+func (b *Bot) applyWizardInput(wizard *searchWizard, message *tgbotapi.Message, step wizardStep, text string) {
+	text = strings.TrimSpace(text)
+	wizard.notice = ""
+
+	var err error
+	switch step {
+	case wizardOrigin, wizardDestination:
+		code, ok := b.resolveCity(message, text, func(code string) {
+			if current := b.activeWizard(wizard.chatID, wizard.userID); current == wizard {
+				b.applyWizardInput(wizard, message, step, code)
+			}
+		})
+		if !ok {
+			return
+		}
+		if step == wizardOrigin {
+			wizard.query.Origins = []string{code}
+		} else {
+			wizard.query.Destination = code
+		}
+	case wizardDates:
+		err = wizard.setDates(text, time.Now())
+	case wizardPrice:
+		var price int
+		price, err = strconv.Atoi(strings.NewReplacer(" ", "", "₽", "").Replace(text))
+		if err != nil || price < 0 {
+			err = fmt.Errorf("некорректная цена: %s", text)
+		} else {
+			wizard.query.MaxPrice = price
+		}
+	default:
+		err = fmt.Errorf("на этом шаге выберите вариант кнопкой")
+	}
+
+	if err != nil {
+		wizard.notice = "❌ " + html.EscapeString(err.Error())
+	} else if step == wizard.step {
+		wizard.step++
+	} else {
+		wizard.notice = "✏️ Исправление учтено"
+	}
+	b.renderWizard(wizard)
+}
+
+// setDates принимает число месяцев или даты в формате фильтра дат
+func (w *searchWizard) setDates(text string, now time.Time) error {
+	if months, err := ParseMonths(text); err == nil {
+		w.query.MonthsToSearch = months
+		w.query.DateFilter = DateFilter{}
+		return nil
+	}
+
+	filter, err := ParseDateFilter(text)
+	if err != nil {
+		return fmt.Errorf("%v. Укажите число месяцев от 1 до %d или даты", err, maxMonthsToSearch)
+	}
+	months, _ := filter.Months(now)
+	if len(months) == 0 {
+		return fmt.Errorf("даты %s уже прошли", filter.String())
+	}
+	if last := searchMonths(now, maxMonthsToSearch); months[len(months)-1] > last[len(last)-1] {
+		return fmt.Errorf("даты дальше %d месяцев вперёд не поддерживаются", maxMonthsToSearch)
+	}
+	w.query.DateFilter = filter
+	w.query.MonthsToSearch = len(months)
+	return nil
+}
+
+// text - сообщение мастера: выбранные параметры и вопрос текущего шага
+func (w *searchWizard) text() string {
+	var sb strings.Builder
+
+	sb.WriteString("🧭 <b>Мастер поиска</b>\n\n")
+	sb.WriteString(w.summary())
+	sb.WriteString("\n\n")
+
+	switch w.step {
+	case wizardOrigin:
+		sb.WriteString("<b>Шаг 1/5.</b> Откуда летим? Выберите город или напишите название.")
+	case wizardDestination:
+		sb.WriteString("<b>Шаг 2/5.</b> Куда летим? Выберите город или напишите название.")
+	case wizardDates:
+		sb.WriteString("<b>Шаг 3/5.</b> На сколько месяцев вперёд искать? Можно написать даты: " +
+			"<code>2026-03-01..2026-03-20</code>, <code>2026-03-01,2026-03-05</code> или <code>2026-03-10±3</code>")
+	case wizardPrice:
+		sb.WriteString("<b>Шаг 4/5.</b> Максимальная цена? Выберите вариант или напишите число.")
+	case wizardDirect:
+		sb.WriteString("<b>Шаг 5/5.</b> Только прямые рейсы?")
+	case wizardConfirm:
+		sb.WriteString("✅ Всё готово. Запускаем поиск?")
+	}
+
+	if w.notice != "" {
+		sb.WriteString("\n\n" + w.notice)
+	}
+	return sb.String()
+}
+
+// summary перечисляет параметры поиска
+func (w *searchWizard) summary() string {
+	query := w.query
+
+	dates := fmt.Sprintf("%d мес.", query.MonthsToSearch)
+	if query.DateFilter.Enabled {
+		dates = query.DateFilter.String()
+	}
+	price := "без ограничения"
+	if query.MaxPrice > 0 {
+		price = fmt.Sprintf("до %d₽", query.MaxPrice)
+	}
+	direct := "с пересадками"
+	if query.Filters.DirectOnly {
+		direct = "только прямые"
+	}
+
+	summary := fmt.Sprintf("🛫 Откуда: <b>%s</b>\n🛬 Куда: <b>%s</b>\n📅 Даты: <b>%s</b>\n💰 Цена: <b>%s</b>\n🔀 Рейсы: <b>%s</b>",
+		strings.Join(query.Origins, "/"),
+		getMetroName(query.Destination),
+		dates,
+		price,
+		direct,
+	)
+
+	// Остальные фильтры мастер не спрашивает, они берутся из настроек по умолчанию
+	others := query.Filters
+	others.DirectOnly = false
+	if !others.Empty() {
+		summary += fmt.Sprintf("\n🎛 Фильтры: <b>%s</b>", html.EscapeString(others.String()))
+	}
+	return summary
+}
+
+// wizardKeyboard строит кнопки для текущего шага мастера
+func (b *Bot) wizardKeyboard(w *searchWizard) tgbotapi.InlineKeyboardMarkup {
+	var buttons []tgbotapi.InlineKeyboardButton
+
+	switch w.step {
+	case wizardOrigin:
+		defaults := b.flightSearch.DefaultQuery().Origins
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("⭐ "+strings.Join(defaults, "/"), "wiz:o:*"))
+		if len(defaults) > 1 {
+			for _, origin := range defaults {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(getMetroName(origin), "wiz:o:"+origin))
+			}
+		}
+	case wizardDestination:
+		for _, destination := range b.wizardDestinations(w.chatID) {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(getMetroName(destination), "wiz:d:"+destination))
+		}
+	case wizardDates:
+		for _, months := range wizardMonths {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d мес.", months), fmt.Sprintf("wiz:m:%d", months)))
+		}
+	case wizardPrice:
+		for _, price := range wizardPrices {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("до %dк", price/1000), fmt.Sprintf("wiz:p:%d", price)))
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("без ограничения", "wiz:p:0"))
+	case wizardDirect:
+		buttons = append(buttons,
+			tgbotapi.NewInlineKeyboardButtonData("✈️ Только прямые", "wiz:direct:1"),
+			tgbotapi.NewInlineKeyboardButtonData("🔀 С пересадками", "wiz:direct:0"))
+	case wizardConfirm:
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("🔍 Искать", "wiz:go"))
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for len(buttons) > 0 {
+		n := min(3, len(buttons))
+		rows = append(rows, buttons[:n])
+		buttons = buttons[n:]
+	}
+
+	// Текущее значение шага можно оставить без изменений
+	var navigation []tgbotapi.InlineKeyboardButton
+	if w.step > wizardOrigin {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("↩️ Назад", "wiz:back"))
+	}
+	if w.step < wizardConfirm {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("⏭ Оставить", "wiz:next"))
+	}
+	navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("✖️ Отмена", "wiz:cancel"))
+	rows = append(rows, navigation)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// wizardDestinations - направления для кнопок: по умолчанию и из подписок чата
+func (b *Bot) wizardDestinations(chatID int64) []string {
+	candidates := []string{b.flightSearch.DefaultQuery().Destination}
+	for _, sub := range b.subscriptions.ForChat(chatID) {
+		candidates = append(candidates, sub.Destination)
+	}
+
+	// Пустой код дал бы кнопку без подписи и данных, и Telegram отклонил бы всю клавиатуру
+	var destinations []string
+	for _, code := range candidates {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || containsString(destinations, code) {
+			continue
+		}
+		destinations = append(destinations, code)
+		if len(destinations) == 6 {
+			break
+		}
+	}
+	return destinations
+}
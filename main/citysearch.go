@@ -0,0 +1,162 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CityMatch - кандидат нечёткого поиска города
+type CityMatch struct {
+	City  City
+	Codes []string // Аэропорты, которые соответствуют запросу
+	Score float64  // От 0 до 1, 1 - точное совпадение
+}
+
+const (
+	minMatchScore     = 0.6  // Ниже этого значения кандидат отбрасывается
+	ambiguityMargin   = 0.05 // Кандидаты с такой разницей в оценке считаются равными
+	maxCityCandidates = 5
+)
+
+// translitTable - упрощённая транслитерация кириллицы в латиницу,
+// достаточная для сравнения "bangkok" с "бангкок" и "moskva" с "москва"
+var translitTable = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+func transliterate(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if latin, ok := translitTable[r]; ok {
+			sb.WriteString(latin)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// compactName убирает пробелы, дефисы и апострофы: "нью-йорк" == "ньюйорк"
+func compactName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '\'', '.':
+			return -1
+		}
+		return r
+	}, normalizeCityName(s))
+}
+
+// SearchCities ранжирует города по сходству с запросом. Учитываются коды IATA,
+// ICAO и коды городов, точное совпадение, префикс, расстояние редактирования
+// и транслитерация между кириллицей и латиницей.
+func (d *AirportDirectory) SearchCities(query string) []CityMatch {
+	compact := compactName(query)
+	if compact == "" {
+		return nil
+	}
+
+	// Коды аэропорта и города дают точное совпадение
+	upper := strings.ToUpper(compact)
+	for _, airport := range d.byIATA {
+		if airport.IATA == upper || airport.ICAO == upper {
+			return []CityMatch{{City: *d.cities[airport.CityCode], Codes: []string{airport.IATA}, Score: 1}}
+		}
+	}
+	if city, ok := d.cities[upper]; ok {
+		return []CityMatch{{City: *city, Codes: append([]string{}, city.Airports...), Score: 1}}
+	}
+
+	latinQuery := transliterate(compact)
+	best := make(map[string]float64)
+
+	for name, cityCode := range d.byName {
+		candidate := compactName(name)
+		score := nameSimilarity(compact, candidate)
+		if translitScore := nameSimilarity(latinQuery, transliterate(candidate)); translitScore > score {
+			score = translitScore
+		}
+		if score > best[cityCode] {
+			best[cityCode] = score
+		}
+	}
+
+	var matches []CityMatch
+	for cityCode, score := range best {
+		if score < minMatchScore {
+			continue
+		}
+		city := d.cities[cityCode]
+		matches = append(matches, CityMatch{City: *city, Codes: append([]string{}, city.Airports...), Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].City.NameRu < matches[j].City.NameRu
+	})
+	if len(matches) > maxCityCandidates {
+		matches = matches[:maxCityCandidates]
+	}
+	return matches
+}
+
+// Ambiguous сообщает, что у лучшего кандидата есть близкие по оценке соперники
+// и стоит уточнить у пользователя, какой город он имел в виду
+func Ambiguous(matches []CityMatch) bool {
+	if len(matches) < 2 || (matches[0].Score == 1 && matches[1].Score < 1) {
+		return false
+	}
+	return matches[0].Score-matches[1].Score <= ambiguityMargin
+}
+
+// nameSimilarity оценивает сходство запроса и названия от 0 до 1
+func nameSimilarity(query string, name string) float64 {
+	if query == name {
+		return 1
+	}
+
+	queryLen := utf8.RuneCountInString(query)
+	nameLen := utf8.RuneCountInString(name)
+
+	// Начало названия: "бангк" -> "бангкок". Короткие префиксы слишком неоднозначны.
+	if queryLen >= 3 && strings.HasPrefix(name, query) {
+		return 0.9 - 0.2*float64(nameLen-queryLen)/float64(nameLen)
+	}
+
+	distance := levenshtein(query, name)
+	longest := queryLen
+	if nameLen > longest {
+		longest = nameLen
+	}
+	return 1 - float64(distance)/float64(longest)
+}
+
+// levenshtein - расстояние редактирования по символам Unicode
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// anyArgs - значение MaxArgs для команд без ограничения числа аргументов
+const anyArgs = -1
+
+// commandHandler обрабатывает команду; args - аргументы после команды
+// (и после подкоманды, если она есть)
+type commandHandler func(b *Bot, message *tgbotapi.Message, args []string)
+
+// Command описывает команду бота или подкоманду. Справка по командам и
+// проверка числа аргументов строятся по этому описанию.
+type Command struct {
+	Name        string
+	Aliases     []string
+	Args        string // Аргументы для справки: "город [месяцев]"
+	Description string
+	Examples    []string
+	MinArgs     int
+	MaxArgs     int // anyArgs - без ограничения
+	Hidden      bool
+	Access      CommandAccess
+	Handler     commandHandler
+	Subcommands []Command
+}
+
+// CommandAccess - кому доступна команда в разрешенном чате
+type CommandAccess int
+
+const (
+	AccessMember    CommandAccess = iota // Любому участнику
+	AccessChatAdmin                      // Меняет настройки чата: в группе - ее администраторам
+	AccessBotAdmin                       // Меняет общие настройки бота: только ADMIN_USER_IDS
+)
+
+// Denied - ответ пользователю без нужных прав
+func (a CommandAccess) Denied() string {
+	if a == AccessBotAdmin {
+		return "Эта команда меняет общие настройки бота и доступна только его администраторам."
+	}
+	return "Настройки группы могут менять только ее администраторы."
+}
+
+// hasAccess проверяет, может ли автор сообщения выполнить команду с таким доступом
+func (b *Bot) hasAccess(message *tgbotapi.Message, access CommandAccess) bool {
+	switch access {
+	case AccessChatAdmin:
+		return b.canManageChat(message)
+	case AccessBotAdmin:
+		return message.From != nil && b.isUserAllowed(message.From.ID)
+	}
+	return true
+}
+
+// botCommands возвращает реестр команд бота. Функция, а не переменная пакета,
+// потому что обработчик /help сам обращается к реестру.
+func botCommands() []Command {
+	return []Command{
+		{
+			Name:        "start",
+			Description: "Начать работу с ботом",
+			MaxArgs:     anyArgs,
+			Hidden:      true,
+			Handler:     (*Bot).handleStart,
+		},
+		{
+			Name:        "search",
+			Aliases:     []string{"find", "поиск"},
+			Args:        "[город] [месяцев|даты]",
+			Description: "Запустить поиск билетов",
+			Examples:    []string{"/search бангкок", "/search BKK 3", "/search бали 2026-12-20±3"},
+			MaxArgs:     2,
+			Handler:     (*Bot).handleSearch,
+		},
+		{
+			Name:        "wizard",
+			Aliases:     []string{"new"},
+			Description: "Пошаговый поиск с кнопками",
+			Handler:     (*Bot).handleWizard,
+		},
+		{
+			Name:        "trip",
+			Args:        "[город] [ночей] [месяцев|даты]",
+			Description: "Поиск туда-обратно",
+			Examples:    []string{"/trip бали 7-14 3"},
+			MaxArgs:     3,
+			Handler:     (*Bot).handleTrip,
+		},
+		{
+			Name:        "weekend",
+			Args:        "[город...] [выходных]",
+			Description: "Поездки на ближайшие выходные",
+			Examples:    []string{"/weekend", "/weekend бангкок пхукет 6"},
+			MaxArgs:     anyArgs,
+			Handler:     (*Bot).handleWeekend,
+		},
+		{
+			Name:        "explore",
+			Args:        "[бюджет] [месяц[..месяц]]",
+			Description: "Куда можно улететь в пределах бюджета",
+			Examples:    []string{"/explore 20000 2026-11", "/explore 30000 2026-11..2027-01"},
+			MaxArgs:     2,
+			Handler:     (*Bot).handleExplore,
+		},
+		{
+			Name:        "watch",
+			Args:        "город [цена] [месяцев] [ночей] [даты]",
+			Description: "Отслеживать маршрут",
+			Examples: []string{
+				"/watch бали 35000 6",
+				"/watch бали 70000 3 7-14",
+				"/watch бали 35000 3 2026-03-01..2026-03-20",
+			},
+			MinArgs: 1,
+			MaxArgs: 5,
+			Access:  AccessChatAdmin,
+			Handler: (*Bot).handleWatch,
+		},
+		{
+			Name:        "watches",
+			Description: "Ваши подписки",
+			Handler:     (*Bot).handleWatches,
+		},
+		{
+			Name:        "unwatch",
+			Args:        "номер",
+			Description: "Удалить подписку",
+			Examples:    []string{"/unwatch 3"},
+			MinArgs:     1,
+			MaxArgs:     1,
+			Access:      AccessChatAdmin,
+			Handler:     (*Bot).handleUnwatch,
+		},
+		{
+			Name:        "schedule",
+			Description: "Расписание проверок подписок",
+			Handler:     (*Bot).handleScheduleList,
+			Subcommands: []Command{
+				{
+					Name:        "set",
+					Args:        "номер|all расписание",
+					Description: "Задать расписание: hourly, daily [ЧЧ:ММ], weekly [день] [ЧЧ:ММ], every 6h или cron-выражение",
+					Examples: []string{
+						"/schedule set 3 daily 08:30",
+						"/schedule set all weekly пт 18:00",
+						"/schedule set 3 0 9,21 * * *",
+					},
+					MinArgs: 2,
+					MaxArgs: anyArgs,
+					Access:  AccessChatAdmin,
+					Handler: (*Bot).handleScheduleSet,
+				},
+				{
+					Name:        "reset",
+					Args:        "номер|all",
+					Description: "Вернуть расписание по умолчанию",
+					Examples:    []string{"/schedule reset all"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleScheduleReset,
+				},
+				{
+					Name:        "tz",
+					Aliases:     []string{"timezone"},
+					Args:        "зона|reset",
+					Description: "Часовой пояс расписаний чата",
+					Examples:    []string{"/schedule tz Asia/Novosibirsk"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleScheduleTimezone,
+				},
+				{
+					Name:        "list",
+					Description: "Показать расписания и ближайшие проверки",
+					Handler:     (*Bot).handleScheduleList,
+				},
+			},
+		},
+		{
+			Name:        "notify",
+			Description: "Тихие часы, дайджест и каналы уведомлений",
+			Handler:     (*Bot).handleNotifyList,
+			Subcommands: []Command{
+				{
+					Name:        "quiet",
+					Args:        "ЧЧ:ММ-ЧЧ:ММ|off",
+					Description: "Тихие часы: обычные уведомления откладываются до их конца",
+					Examples:    []string{"/notify quiet 23:00-08:00"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleNotifyQuiet,
+				},
+				{
+					Name:        "digest",
+					Args:        "daily [ЧЧ:ММ]|weekly [день] [ЧЧ:ММ]|off",
+					Description: "Присылать обычные уведомления одним сообщением без звука",
+					Examples:    []string{"/notify digest daily 09:00", "/notify digest weekly сб 10:00"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleNotifyDigest,
+				},
+				{
+					Name:        "sinks",
+					Args:        "номер|all канал [канал...]|default",
+					Description: "Куда присылать уведомления подписки: telegram, email, slack, discord, webhook",
+					Examples:    []string{"/notify sinks all telegram email", "/notify sinks 2 default"},
+					MinArgs:     2,
+					MaxArgs:     anyArgs,
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleNotifySinks,
+				},
+				{
+					Name:        "list",
+					Description: "Показать настройки уведомлений",
+					Handler:     (*Bot).handleNotifyList,
+				},
+			},
+		},
+		{
+			Name:        "origin",
+			Description: "Города вылета по умолчанию",
+			Handler:     (*Bot).handleOriginList,
+			Subcommands: []Command{
+				{
+					Name:        "set",
+					Args:        "город [город...]",
+					Description: "Заменить список городов вылета",
+					Examples:    []string{"/origin set новосибирск барнаул"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessBotAdmin,
+					Handler:     (*Bot).handleOriginSet,
+				},
+				{
+					Name:        "add",
+					Args:        "город [город...]",
+					Description: "Добавить города вылета",
+					Examples:    []string{"/origin add москва"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessBotAdmin,
+					Handler:     (*Bot).handleOriginAdd,
+				},
+				{
+					Name:        "remove",
+					Aliases:     []string{"rm", "del"},
+					Args:        "город [город...]",
+					Description: "Убрать города вылета",
+					Examples:    []string{"/origin remove BAX"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessBotAdmin,
+					Handler:     (*Bot).handleOriginRemove,
+				},
+				{
+					Name:        "list",
+					Description: "Показать города вылета",
+					Handler:     (*Bot).handleOriginList,
+				},
+			},
+		},
+		{
+			Name:        "dest",
+			Aliases:     []string{"destination"},
+			Description: "Направление по умолчанию",
+			Handler:     (*Bot).handleDestList,
+			Subcommands: []Command{
+				{
+					Name:        "set",
+					Args:        "город",
+					Description: "Сменить направление по умолчанию",
+					Examples:    []string{"/dest set бали"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessBotAdmin,
+					Handler:     (*Bot).handleDestSet,
+				},
+				{
+					Name:        "list",
+					Description: "Показать направление и направления подписок",
+					Handler:     (*Bot).handleDestList,
+				},
+			},
+		},
+		{
+			Name:        "filter",
+			Aliases:     []string{"filters"},
+			Description: "Фильтры рейсов чата",
+			Handler:     (*Bot).handleFilterList,
+			Subcommands: []Command{
+				{
+					Name:        "transfers",
+					Args:        "число|прямые|any",
+					Description: "Сколько пересадок допустимо, 0 - только прямые",
+					Examples:    []string{"/filter transfers 1"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("transfers"),
+				},
+				{
+					Name:        "layover",
+					Args:        "часы|any",
+					Description: "Сколько можно ждать на пересадках, оценка по длительности перелёта",
+					Examples:    []string{"/filter layover 4", "/filter layover 2ч30м"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("layover"),
+				},
+				{
+					Name:        "airlines",
+					Args:        "код [код...]|any",
+					Description: "Только эти авиакомпании",
+					Examples:    []string{"/filter airlines SU S7"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("airlines"),
+				},
+				{
+					Name:        "skip-airlines",
+					Args:        "код [код...]|any",
+					Description: "Исключить авиакомпании",
+					Examples:    []string{"/filter skip-airlines UT DP"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("skip-airlines"),
+				},
+				{
+					Name:        "departure",
+					Args:        "ЧЧ:ММ-ЧЧ:ММ|any",
+					Description: "Время вылета",
+					Examples:    []string{"/filter departure 06:00-23:00"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("departure"),
+				},
+				{
+					Name:        "arrival",
+					Args:        "ЧЧ:ММ-ЧЧ:ММ|any",
+					Description: "Местное время прилёта",
+					Examples:    []string{"/filter arrival 08:00-22:00"},
+					MinArgs:     1,
+					MaxArgs:     1,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("arrival"),
+				},
+				{
+					Name:        "skip-days",
+					Args:        "день [день...]|any",
+					Description: "Не вылетать в эти дни недели",
+					Examples:    []string{"/filter skip-days сб вс"},
+					MinArgs:     1,
+					MaxArgs:     anyArgs,
+					Access:      AccessChatAdmin,
+					Handler:     filterHandler("skip-days"),
+				},
+				{
+					Name:        "reset",
+					Description: "Вернуть фильтры по умолчанию",
+					Access:      AccessChatAdmin,
+					Handler:     (*Bot).handleFilterReset,
+				},
+				{
+					Name:        "list",
+					Description: "Показать фильтры",
+					Handler:     (*Bot).handleFilterList,
+				},
+			},
+		},
+		{
+			Name:        "cities",
+			Args:        "[страна]",
+			Description: "Список доступных городов",
+			Examples:    []string{"/cities", "/cities таиланд", "/cities ID"},
+			MaxArgs:     anyArgs,
+			Handler:     (*Bot).handleCities,
+		},
+		{
+			Name:        "status",
+			Aliases:     []string{"статус"},
+			Description: "Показать статус бота",
+			Handler:     (*Bot).handleStatus,
+		},
+		{
+			Name:        "help",
+			Aliases:     []string{"помощь"},
+			Args:        "[команда]",
+			Description: "Справка, по команде - подробная",
+			MaxArgs:     1,
+			Handler:     (*Bot).handleHelp,
+		},
+	}
+}
+
+// findCommand ищет команду по имени или синониму без учета регистра
+func findCommand(commands []Command, name string) (Command, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+		for _, alias := range command.Aliases {
+			if alias == name {
+				return command, true
+			}
+		}
+	}
+	return Command{}, false
+}
+
+// handleMessage направляет команду обработчику из реестра
+func (b *Bot) handleMessage(message *tgbotapi.Message) {
+	command, ok := findCommand(botCommands(), message.Command())
+	if !ok {
+		// В группе незнакомая команда без упоминания бота может быть адресована другому боту
+		if message.Chat.IsPrivate() || strings.Contains(message.CommandWithAt(), "@") {
+			b.handleUnknown(message)
+		}
+		return
+	}
+
+	b.dispatchCommand(message, command, "/"+command.Name, strings.Fields(message.CommandArguments()))
+}
+
+// dispatchCommand выбирает подкоманду, проверяет число аргументов и вызывает обработчик.
+// path - полное имя команды для справки: "/origin set".
+func (b *Bot) dispatchCommand(message *tgbotapi.Message, command Command, path string, args []string) {
+	if len(command.Subcommands) > 0 {
+		// Без подкоманды выполняется обработчик самой команды, если он есть
+		if len(args) == 0 && command.Handler != nil {
+			command.Handler(b, message, args)
+			return
+		}
+		if len(args) == 0 {
+			b.replyHTML(message.Chat.ID, "❌ Укажите подкоманду.\n\n"+commandUsageHTML(path, command))
+			return
+		}
+
+		sub, ok := findCommand(command.Subcommands, args[0])
+		if !ok {
+			b.replyHTML(message.Chat.ID, fmt.Sprintf("❌ Неизвестная подкоманда <code>%s</code>.\n\n%s",
+				html.EscapeString(args[0]), commandUsageHTML(path, command)))
+			return
+		}
+		b.dispatchCommand(message, sub, path+" "+sub.Name, args[1:])
+		return
+	}
+
+	if len(args) < command.MinArgs {
+		b.replyHTML(message.Chat.ID, "❌ Не хватает аргументов.\n\n"+commandUsageHTML(path, command))
+		return
+	}
+	if command.MaxArgs != anyArgs && len(args) > command.MaxArgs {
+		b.replyHTML(message.Chat.ID, "❌ Слишком много аргументов.\n\n"+commandUsageHTML(path, command))
+		return
+	}
+
+	if !b.hasAccess(message, command.Access) {
+		b.replyHTML(message.Chat.ID, "❌ "+command.Access.Denied())
+		return
+	}
+
+	command.Handler(b, message, args)
+}
+
+// commandLineHTML - строка справки: "/search [город] [месяцев] - Запустить поиск билетов"
+func commandLineHTML(path string, command Command) string {
+	line := path
+	if command.Args != "" {
+		line += " " + html.EscapeString(command.Args)
+	}
+	return line + " - " + command.Description
+}
+
+// commandUsageHTML - подробная справка по команде с подкомандами и примерами
+func commandUsageHTML(path string, command Command) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("ℹ️ <b>%s</b> - %s\n", path, command.Description))
+	if command.Handler != nil && (command.Args != "" || len(command.Subcommands) == 0) {
+		sb.WriteString("<code>" + path)
+		if command.Args != "" {
+			sb.WriteString(" " + html.EscapeString(command.Args))
+		}
+		sb.WriteString("</code>\n")
+	}
+	if len(command.Aliases) > 0 {
+		sb.WriteString(fmt.Sprintf("Синонимы: /%s\n", strings.Join(command.Aliases, ", /")))
+	}
+
+	if len(command.Subcommands) > 0 {
+		sb.WriteString("\n<b>Подкоманды:</b>\n")
+		for _, sub := range command.Subcommands {
+			sb.WriteString(commandLineHTML(path+" "+sub.Name, sub) + "\n")
+		}
+	}
+
+	var examples []string
+	examples = append(examples, command.Examples...)
+	for _, sub := range command.Subcommands {
+		examples = append(examples, sub.Examples...)
+	}
+	if len(examples) > 0 {
+		sb.WriteString("\n<b>Примеры:</b>\n")
+		for _, example := range examples {
+			sb.WriteString("<code>" + html.EscapeString(example) + "</code>\n")
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// commandsHelpHTML - краткий список всех команд реестра
+func commandsHelpHTML() string {
+	var lines []string
+	for _, command := range botCommands() {
+		if command.Hidden {
+			continue
+		}
+		if len(command.Subcommands) == 0 {
+			lines = append(lines, commandLineHTML("/"+command.Name, command))
+			continue
+		}
+		names := make([]string, 0, len(command.Subcommands))
+		for _, sub := range command.Subcommands {
+			names = append(names, sub.Name)
+		}
+		lines = append(lines, fmt.Sprintf("/%s %s - %s", command.Name, strings.Join(names, "|"), command.Description))
+	}
+	return strings.Join(lines, "\n")
+}
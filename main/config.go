@@ -10,28 +10,66 @@ import (
 	"github.com/joho/godotenv"
 )
 
-// AppConfig содержит все настройки приложения
+// AppConfig содержит все настройки приложения.
+// После загрузки конфигурация только читается и безопасна для параллельного доступа.
 type AppConfig struct {
-	TelegramBotUrl        string
-	TelegramBotToken      string
-	TelegramChatID        string
-	AdminUsers            []int64
-	TravelPayoutsToken    string
-	TravelPayoutsUrlPrice string
-	OriginIATA            []string
-	DestinationIATA       string
-	MaxPrice              int
-	MonthsToSearch        int
-	MaxFlightTime         int
-	DateFilter            DateFilter
+	TelegramBotUrl         string
+	TelegramBotToken       string
+	TelegramChatID         string  // Канал или группа для рассылки: "-1001234567890" или "@channel"
+	TelegramThreadID       int     // Тема форума в группе TelegramChatID
+	GroupChats             []int64 // Группы, где ботом пользуются все участники, а не только ADMIN_USER_IDS
+	AdminUsers             []int64
+	TravelPayoutsToken     string
+	TravelPayoutsUrlPrice  string
+	TravelPayoutsUrlLatest string
+	FareProviders          []string
+	ProviderRateLimit      float64
+	ProviderBurst          int
+	SearchWorkers          int
+	ProviderRetry          RetryPolicy
+	OriginIATA             []string
+	DestinationIATA        string
+	MaxPrice               int
+	MonthsToSearch         int
+	MaxFlightTime          int
+	DateFilter             DateFilter
+	Filters                FareFilters
+	RoundTrip              bool
+	MinNights              int
+	MaxNights              int
+	WeekendsToSearch       int
+	WeekendRules           WeekendRules
+	HistoryPath            string
+	HistoryRetentionDays   int
+	SubscriptionsPath      string
+	OutboxPath             string
+	LedgerPath             string
+	Ledger                 LedgerPolicy
+	Notifiers              []string // Включенные каналы уведомлений: telegram, email, slack, discord, webhook
+	DefaultSinks           []string // Каналы подписок, у которых они не выбраны
+	NotifyRetry            RetryPolicy
+	Email                  EmailConfig
+	SlackWebhookURL        string
+	DiscordWebhookURL      string
+	WebhookURL             string
+	WebhookFormat          NotificationFormat
+	Schedule               string // cron-выражение проверки подписок по умолчанию
+	ScheduleTimezone       string
+	AirportsFile           string
+	AlertThresholds        AlertThresholds
 }
 
 type DateFilter struct {
-	StartDate time.Time // Начало периода
-	EndDate   time.Time // Конец периода
-	Dates     []string  // Конкретные даты (позже)
-	Enabled   bool      // Включен ли фильтр
-	Mode      string    // "range" или "list"
+	StartDate time.Time `json:"start_date"` // Начало периода
+	EndDate   time.Time `json:"end_date"`   // Конец периода
+	Dates     []string  `json:"dates"`      // Конкретные даты (позже)
+	Enabled   bool      `json:"enabled"`    // Включен ли фильтр
+	Mode      string    `json:"mode"`       // "range", "list" или "flex"
+
+	// Режим "flex": желаемая дата и допустимое отклонение в днях,
+	// StartDate и EndDate при этом задают границы окна
+	Target   time.Time `json:"target,omitempty"`
+	FlexDays int       `json:"flex_days,omitempty"`
 }
 
 func loadConfig() (*AppConfig, error) {
@@ -40,16 +78,6 @@ func loadConfig() (*AppConfig, error) {
 		log.Println("Файл .env не найден, используем переменные окружения")
 	}
 
-	var adminUsers []int64
-	if adminIDs := getEnv("ADMIN_USER_IDS", ""); adminIDs != "" {
-		ids := strings.Split(adminIDs, ",")
-		for _, idStr := range ids {
-			if id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64); err == nil {
-				adminUsers = append(adminUsers, id)
-			}
-		}
-	}
-
 	dateFilter := DateFilter{
 		Enabled: false,
 		Mode:    "range",
@@ -83,19 +111,154 @@ func loadConfig() (*AppConfig, error) {
 		}
 	}
 
+	// Гибкая дата "2026-12-20±3" заменяет остальные настройки фильтра дат
+	if flexStr := getEnv("DATE_FILTER_FLEX", ""); flexStr != "" {
+		if filter, err := ParseDateFilter(flexStr); err == nil && filter.Mode == "flex" {
+			dateFilter = filter
+		} else {
+			log.Printf("Некорректный DATE_FILTER_FLEX %q, ожидается дата±дней", flexStr)
+		}
+	}
+
+	// Фильтры рейсов: FILTER_TRANSFERS=0, FILTER_SKIP_AIRLINES=UT,DP, FILTER_DEPARTURE=06:00-23:00, ...
+	var filters FareFilters
+	for _, name := range fareFilterNames {
+		key := "FILTER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if value := getEnv(key, ""); value != "" {
+			if err := filters.Set(name, value); err != nil {
+				log.Printf("Некорректный %s %q: %v", key, value, err)
+			}
+		}
+	}
+
+	// Расписание подписок: cron-выражение или "daily 09:00", "weekly пт 18:00", "every 6h"
+	schedule := defaultSchedule
+	if value := getEnv("SCHEDULE", ""); value != "" {
+		if spec, err := ParseSchedule(value); err == nil {
+			schedule = spec
+		} else {
+			log.Printf("Некорректный SCHEDULE %q: %v", value, err)
+		}
+	}
+	var scheduleTimezone string
+	if value := getEnv("SCHEDULE_TZ", ""); value != "" {
+		if timezone, err := ParseTimezone(value); err == nil {
+			scheduleTimezone = timezone
+		} else {
+			log.Printf("Некорректный SCHEDULE_TZ %q: %v", value, err)
+		}
+	}
+
+	weekendRules := DefaultWeekendRules
+	if value := getEnv("WEEKEND_OUTBOUND", ""); value != "" {
+		if windows, err := ParseDepartureWindows(value); err == nil {
+			weekendRules.Outbound = windows
+		} else {
+			log.Printf("Некорректный WEEKEND_OUTBOUND %q: %v", value, err)
+		}
+	}
+	if value := getEnv("WEEKEND_RETURN", ""); value != "" {
+		if windows, err := ParseDepartureWindows(value); err == nil {
+			weekendRules.Return = windows
+		} else {
+			log.Printf("Некорректный WEEKEND_RETURN %q: %v", value, err)
+		}
+	}
+	weekends := getEnvInt("WEEKENDS_TO_SEARCH", 4)
+	if weekends < 1 || weekends > maxWeekends {
+		log.Printf("Некорректный WEEKENDS_TO_SEARCH %d, нужно от 1 до %d, используется 4", weekends, maxWeekends)
+		weekends = 4
+	}
+
+	// Форматы каналов уведомлений: письма - text или html, вебхук - любой
+	emailFormat, webhookFormat := FormatText, FormatJSON
+	if value := getEnv("SMTP_FORMAT", ""); value != "" {
+		if format, err := ParseNotificationFormat(value); err == nil {
+			emailFormat = format
+		} else {
+			log.Printf("Некорректный SMTP_FORMAT %q: %v", value, err)
+		}
+	}
+	if value := getEnv("WEBHOOK_FORMAT", ""); value != "" {
+		if format, err := ParseNotificationFormat(value); err == nil {
+			webhookFormat = format
+		} else {
+			log.Printf("Некорректный WEBHOOK_FORMAT %q: %v", value, err)
+		}
+	}
+
 	return &AppConfig{
-		TelegramBotUrl:        os.Getenv("TELEGRAM_BOT_URL"),
-		TelegramBotToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
-		TelegramChatID:        os.Getenv("TELEGRAM_CHAT_ID"),
-		TravelPayoutsToken:    os.Getenv("TRAVELPAYOUTS_TOKEN"),
-		TravelPayoutsUrlPrice: os.Getenv("TRAVELPAYOUTS_URL_PRICE"),
-		OriginIATA:            getEnvStringArray("ORIGIN_IATA", []string{""}),
-		DestinationIATA:       os.Getenv("DESTINATION_IATA"),
-		MaxPrice:              getEnvInt("MAX_PRICE", 30000),
-		MonthsToSearch:        getEnvInt("MONTHS_TO_SEARCH", 3),
-		AdminUsers:            adminUsers,
-		MaxFlightTime:         getEnvInt("MAX_FLIGHT_TIME", 1440),
-		DateFilter:            dateFilter,
+		TelegramBotUrl:         os.Getenv("TELEGRAM_BOT_URL"),
+		TelegramBotToken:       os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramChatID:         os.Getenv("TELEGRAM_CHAT_ID"),
+		TelegramThreadID:       getEnvInt("TELEGRAM_THREAD_ID", 0),
+		GroupChats:             getEnvInt64Array("GROUP_CHAT_IDS"),
+		TravelPayoutsToken:     os.Getenv("TRAVELPAYOUTS_TOKEN"),
+		TravelPayoutsUrlPrice:  os.Getenv("TRAVELPAYOUTS_URL_PRICE"),
+		TravelPayoutsUrlLatest: getEnv("TRAVELPAYOUTS_URL_LATEST", "https://api.travelpayouts.com/aviasales/v3/get_latest_prices"),
+		FareProviders:          getEnvStringArray("FARE_PROVIDERS", []string{"travelpayouts"}),
+		ProviderRateLimit:      getEnvFloat("PROVIDER_RATE_LIMIT", 5),
+		ProviderBurst:          getEnvInt("PROVIDER_BURST", 5),
+		SearchWorkers:          getEnvInt("SEARCH_WORKERS", 6),
+		ProviderRetry: RetryPolicy{
+			MaxAttempts: getEnvInt("PROVIDER_MAX_ATTEMPTS", 3),
+			BaseDelay:   time.Duration(getEnvInt("PROVIDER_RETRY_BASE_MS", 500)) * time.Millisecond,
+			MaxDelay:    time.Duration(getEnvInt("PROVIDER_RETRY_MAX_MS", 8000)) * time.Millisecond,
+		},
+		OriginIATA:           getEnvStringArray("ORIGIN_IATA", []string{""}),
+		DestinationIATA:      os.Getenv("DESTINATION_IATA"),
+		MaxPrice:             getEnvInt("MAX_PRICE", 30000),
+		MonthsToSearch:       getEnvInt("MONTHS_TO_SEARCH", 3),
+		AdminUsers:           getEnvInt64Array("ADMIN_USER_IDS"),
+		MaxFlightTime:        getEnvInt("MAX_FLIGHT_TIME", 1440),
+		DateFilter:           dateFilter,
+		Filters:              filters,
+		RoundTrip:            getEnvBool("ROUND_TRIP", false),
+		MinNights:            getEnvInt("ROUND_TRIP_MIN_NIGHTS", 7),
+		MaxNights:            getEnvInt("ROUND_TRIP_MAX_NIGHTS", 14),
+		WeekendsToSearch:     weekends,
+		WeekendRules:         weekendRules,
+		HistoryPath:          getEnv("HISTORY_PATH", "data/price_history.jsonl"),
+		HistoryRetentionDays: getEnvInt("HISTORY_RETENTION_DAYS", 365),
+		SubscriptionsPath:    getEnv("SUBSCRIPTIONS_PATH", "data/subscriptions.json"),
+		OutboxPath:           getEnv("OUTBOX_PATH", "data/outbox.json"),
+		LedgerPath:           getEnv("LEDGER_PATH", "data/notified.json"),
+		Ledger: LedgerPolicy{
+			Window:           time.Duration(getEnvInt("NOTIFY_REPEAT_HOURS", 72)) * time.Hour,
+			MinChangeRub:     getEnvInt("NOTIFY_MIN_CHANGE_RUB", 0),
+			MinChangePercent: getEnvInt("NOTIFY_MIN_CHANGE_PERCENT", 3),
+		},
+		Notifiers:    getEnvStringArray("NOTIFIERS", []string{"telegram"}),
+		DefaultSinks: getEnvStringArray("NOTIFY_SINKS", []string{"telegram"}),
+		NotifyRetry: RetryPolicy{
+			MaxAttempts: getEnvInt("NOTIFY_MAX_ATTEMPTS", 3),
+			BaseDelay:   time.Duration(getEnvInt("NOTIFY_RETRY_BASE_MS", 1000)) * time.Millisecond,
+			MaxDelay:    time.Duration(getEnvInt("NOTIFY_RETRY_MAX_MS", 30000)) * time.Millisecond,
+		},
+		Email: EmailConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getEnvInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       getEnvStringArray("SMTP_TO", nil),
+			Format:   emailFormat,
+		},
+		SlackWebhookURL:   os.Getenv("SLACK_WEBHOOK_URL"),
+		DiscordWebhookURL: os.Getenv("DISCORD_WEBHOOK_URL"),
+		WebhookURL:        os.Getenv("WEBHOOK_URL"),
+		WebhookFormat:     webhookFormat,
+		Schedule:          schedule,
+		ScheduleTimezone:  scheduleTimezone,
+		AirportsFile:      os.Getenv("AIRPORTS_FILE"),
+		AlertThresholds: AlertThresholds{
+			MinDropRub:       getEnvInt("ALERT_MIN_DROP_RUB", 0),
+			MinDropPercent:   getEnvInt("ALERT_MIN_DROP_PERCENT", 5),
+			NotifyAllTimeLow: getEnvBool("ALERT_ALL_TIME_LOW", true),
+
+			UrgentAllTimeLow:  getEnvBool("ALERT_URGENT_ALL_TIME_LOW", true),
+			UrgentDropPercent: getEnvInt("ALERT_URGENT_DROP_PERCENT", 0),
+		},
 	}, nil
 }
 
@@ -113,6 +276,34 @@ func getEnvInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
 func getEnv(key string, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -129,3 +320,14 @@ func getEnvStringArray(key string, defaultValue []string) []string {
 
 	return strings.Split(value, ",")
 }
+
+// Для списка идентификаторов пользователей и чатов через запятую
+func getEnvInt64Array(key string) []int64 {
+	var ids []int64
+	for _, idStr := range strings.Split(os.Getenv(key), ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// otherCountry - страна для направлений, которых нет в справочнике аэропортов
+const otherCountry = "Другие направления"
+
+// ExploreDestination - самый дешёвый рейс в один город
+type ExploreDestination struct {
+	City    string // Код города назначения
+	Name    string
+	Country string
+	Flight  Flight
+}
+
+// ExploreCountry - направления одной страны, от дешёвых к дорогим
+type ExploreCountry struct {
+	Name         string
+	Destinations []ExploreDestination
+}
+
+// ExploreResult - куда можно улететь из городов вылета в пределах бюджета
+type ExploreResult struct {
+	Origins      []string
+	Months       []string
+	Budget       int
+	Destinations []ExploreDestination // Отсортированы по цене
+	Errors       []LegError
+}
+
+// ByCountry группирует направления по странам; страны упорядочены
+// по самому дешёвому направлению, направления без страны - в конце
+func (r *ExploreResult) ByCountry() []ExploreCountry {
+	var countries []ExploreCountry
+	index := make(map[string]int)
+	for _, destination := range r.Destinations {
+		i, exists := index[destination.Country]
+		if !exists {
+			i = len(countries)
+			index[destination.Country] = i
+			countries = append(countries, ExploreCountry{Name: destination.Country})
+		}
+		countries[i].Destinations = append(countries[i].Destinations, destination)
+	}
+
+	// Направления уже по цене, поэтому порядок появления стран - по самой низкой цене
+	sort.SliceStable(countries, func(i, j int) bool {
+		return countries[i].Name != otherCountry && countries[j].Name == otherCountry
+	})
+	return countries
+}
+
+// ParseExploreMonths разбирает месяц "2006-01" или диапазон "2006-01..2006-03".
+// Месяцы не раньше текущего и не дальше maxMonthsToSearch.
+func ParseExploreMonths(arg string, now time.Time) ([]string, error) {
+	from, to, isRange := strings.Cut(arg, "..")
+	if !isRange {
+		to = from
+	}
+
+	var bounds [2]string
+	for i, value := range []string{from, to} {
+		month, err := time.Parse("2006-01", strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("некорректный месяц: %s, нужен формат 2026-11 или 2026-11..2027-01", value)
+		}
+		bounds[i] = month.Format("2006-01")
+	}
+	if bounds[0] > bounds[1] {
+		return nil, fmt.Errorf("начало периода %s позже конца %s", bounds[0], bounds[1])
+	}
+
+	available := searchMonths(now, maxMonthsToSearch)
+	first, last := available[0], available[len(available)-1]
+	if bounds[0] < first || bounds[1] > last {
+		return nil, fmt.Errorf("период %s вне периода поиска %s – %s", formatMonthRange(bounds[:]), first, last)
+	}
+
+	var months []string
+	for _, month := range available {
+		if month >= bounds[0] && month <= bounds[1] {
+			months = append(months, month)
+		}
+	}
+	return months, nil
+}
+
+// formatMonthRange описывает отсортированные месяцы: "2026-11" или "2026-11 – 2027-01"
+func formatMonthRange(months []string) string {
+	if len(months) == 0 {
+		return ""
+	}
+	if months[0] == months[len(months)-1] {
+		return months[0]
+	}
+	return months[0] + " – " + months[len(months)-1]
+}
+
+// Explore ищет самые дешёвые направления из городов вылета запроса на месяцы months
+// у поставщиков, которые это поддерживают. В результат попадает по одному
+// самому дешёвому рейсу в каждый город за все месяцы не дороже бюджета.
+func (fs *FlightSearch) Explore(ctx context.Context, query SearchQuery, months []string, budget int) (*ExploreResult, error) {
+	result := &ExploreResult{Months: months, Budget: budget}
+
+	// Поставщик принимает коды городов, отдельные аэропорты не нужны
+	for _, origin := range query.Origins {
+		if city := airports.CityCode(origin); !containsString(result.Origins, city) {
+			result.Origins = append(result.Origins, city)
+		}
+	}
+	if len(result.Origins) == 0 {
+		return nil, fmt.Errorf("не заданы города вылета")
+	}
+	if len(months) == 0 {
+		return nil, fmt.Errorf("не заданы месяцы поиска")
+	}
+
+	var legs []searchLeg
+	for _, origin := range result.Origins {
+		for _, month := range months {
+			for _, leg := range fs.legs(query, origin, "", month, false) {
+				leg.explore = true
+				legs = append(legs, leg)
+			}
+		}
+	}
+
+	cheapest := make(map[string]Flight)
+	supported := 0
+	for _, leg := range fs.runLegs(ctx, legs, time.Now()) {
+		if errors.Is(leg.err, errExploreUnsupported) {
+			continue
+		}
+		supported++
+
+		if leg.err != nil {
+			result.Errors = append(result.Errors, LegError{
+				Provider:    leg.provider.Name(),
+				Origin:      leg.origin,
+				Destination: "везде",
+				Month:       leg.month,
+				Err:         leg.err,
+			})
+			continue
+		}
+
+		for _, flight := range leg.flights {
+			city := airports.CityCode(flight.Destination)
+			if containsString(result.Origins, city) || (budget > 0 && flight.Price > budget) {
+				continue
+			}
+			if !query.Filters.AllowsTransfers(flight.Transfers) {
+				continue
+			}
+			if current, exists := cheapest[city]; !exists || flight.Price < current.Price {
+				cheapest[city] = flight
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if supported == 0 {
+		return nil, errExploreUnsupported
+	}
+	if len(result.Errors) == supported {
+		return nil, fmt.Errorf("ни один запрос к поставщикам не выполнен: %w", result.Errors[0].Err)
+	}
+
+	for code, flight := range cheapest {
+		destination := ExploreDestination{City: code, Name: code, Country: otherCountry, Flight: flight}
+		if city, ok := airports.City(code); ok {
+			destination.Name, destination.Country = city.NameRu, city.CountryRu
+		}
+		result.Destinations = append(result.Destinations, destination)
+	}
+	sort.Slice(result.Destinations, func(i, j int) bool {
+		a, b := result.Destinations[i], result.Destinations[j]
+		if a.Flight.Price != b.Flight.Price {
+			return a.Flight.Price < b.Flight.Price
+		}
+		return a.City < b.City
+	})
+
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseExploreMonths(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"2026-11", []string{"2026-11"}},
+		{"2026-11..2027-01", []string{"2026-11", "2026-12", "2027-01"}},
+		{"2026-10..2026-10", []string{"2026-10"}},
+		{"2026-09", nil},          // прошедший месяц
+		{"2027-10", nil},          // дальше периода поиска
+		{"2027-01..2026-11", nil}, // начало позже конца
+		{"ноябрь", nil},
+		{"2026-11..", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseExploreMonths(tt.arg, now)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%q: ожидалась ошибка, получено %v", tt.arg, got)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%q: %v (%v), ожидалось %v", tt.arg, got, err, tt.want)
+		}
+	}
+}
+
+func TestExploreKeepsCheapestFareAcrossMonths(t *testing.T) {
+	prices := map[string]int{"2026-11": 9000, "2026-12": 7000, "2027-01": 8000}
+	provider := &fakeProvider{name: "fake", fares: func(query FareQuery) ([]Flight, error) {
+		query.Destination = "AER"
+		return []Flight{fakeFlight(query, prices[query.Month], "S7")}, nil
+	}}
+	search := newTestFlightSearch(provider)
+
+	months := []string{"2026-11", "2026-12", "2027-01"}
+	result, err := search.Explore(context.Background(), search.DefaultQuery(), months, 10000)
+	if err != nil {
+		t.Fatalf("Explore: %v", err)
+	}
+
+	want := []string{"OVB--2026-11", "OVB--2026-12", "OVB--2027-01"}
+	if got := provider.Calls(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("запросы %v, ожидались %v", got, want)
+	}
+	if len(result.Destinations) != 1 {
+		t.Fatalf("направлений %d, ожидалось одно: %+v", len(result.Destinations), result.Destinations)
+	}
+	if flight := result.Destinations[0].Flight; flight.Price != 7000 || flight.DepartureAt.Format("2006-01") != "2026-12" {
+		t.Errorf("выбран рейс %s за %d₽, ожидался самый дешёвый в 2026-12 за 7000₽", flight.DepartureDate, flight.Price)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeWindow - интервал времени суток "15:04"–"15:04", границы включительно.
+// Если From позже To, интервал проходит через полночь: 22:00–06:00.
+// Пустой интервал не ограничивает время.
+type TimeWindow struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Empty сообщает, что интервал не задан
+func (w TimeWindow) Empty() bool {
+	return w.From == "" && w.To == ""
+}
+
+// Contains проверяет время суток "15:04"
+func (w TimeWindow) Contains(clock string) bool {
+	if w.Empty() {
+		return true
+	}
+	if w.From <= w.To {
+		return clock >= w.From && clock <= w.To
+	}
+	return clock >= w.From || clock <= w.To
+}
+
+func (w TimeWindow) String() string {
+	return w.From + "–" + w.To
+}
+
+// ParseTimeWindow разбирает интервал "06:00-23:00"
+func ParseTimeWindow(value string) (TimeWindow, error) {
+	from, to, found := strings.Cut(value, "-")
+	if !found {
+		return TimeWindow{}, fmt.Errorf("некорректный интервал времени: %s, нужен формат 06:00-23:00", value)
+	}
+
+	window := TimeWindow{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+	for _, clock := range []string{window.From, window.To} {
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return TimeWindow{}, fmt.Errorf("некорректное время: %s", clock)
+		}
+	}
+	return window, nil
+}
+
+// FareFilters - ограничения на сам рейс: пересадки и их длительность, авиакомпании,
+// время вылета и прилёта, дни недели. Нулевое значение ничего не ограничивает.
+type FareFilters struct {
+	DirectOnly      bool       `json:"direct_only,omitempty"`   // Только прямые рейсы
+	MaxTransfers    int        `json:"max_transfers,omitempty"` // Пересадок не больше, 0 - не ограничено
+	MaxLayover      int        `json:"max_layover,omitempty"`   // Ожидание на пересадках не дольше, минут
+	Airlines        []string   `json:"airlines,omitempty"`      // Только эти авиакомпании, коды IATA
+	ExcludeAirlines []string   `json:"exclude_airlines,omitempty"`
+	Departure       TimeWindow `json:"departure,omitempty"`        // Местное время вылета
+	Arrival         TimeWindow `json:"arrival,omitempty"`          // Местное время прилёта
+	ExcludeWeekdays []string   `json:"exclude_weekdays,omitempty"` // Дни вылета как в Flight.DayOfWeek: "Сб"
+}
+
+// Clone возвращает копию фильтров, не разделяющую с исходными списки
+func (f FareFilters) Clone() FareFilters {
+	clone := f
+	clone.Airlines = append([]string(nil), f.Airlines...)
+	clone.ExcludeAirlines = append([]string(nil), f.ExcludeAirlines...)
+	clone.ExcludeWeekdays = append([]string(nil), f.ExcludeWeekdays...)
+	return clone
+}
+
+// Empty сообщает, что ни один фильтр не задан
+func (f FareFilters) Empty() bool {
+	return !f.DirectOnly && f.MaxTransfers == 0 && f.MaxLayover == 0 && len(f.Airlines) == 0 && len(f.ExcludeAirlines) == 0 &&
+		f.Departure.Empty() && f.Arrival.Empty() && len(f.ExcludeWeekdays) == 0
+}
+
+// AllowsTransfers проверяет только ограничение на число пересадок
+func (f FareFilters) AllowsTransfers(transfers int) bool {
+	if f.DirectOnly {
+		return transfers == 0
+	}
+	return f.MaxTransfers == 0 || transfers <= f.MaxTransfers
+}
+
+// Matches проверяет рейс по всем фильтрам. Время прилёта считается
+// по длительности перелёта в часовом поясе аэропорта назначения.
+func (f FareFilters) Matches(flight Flight) bool {
+	if !f.AllowsTransfers(flight.Transfers) {
+		return false
+	}
+	if f.MaxLayover > 0 && flight.Transfers > 0 {
+		layover, ok := layoverTime(flight)
+		if !ok || layover > time.Duration(f.MaxLayover)*time.Minute {
+			return false
+		}
+	}
+	if len(f.Airlines) > 0 && !containsString(f.Airlines, flight.Airline) {
+		return false
+	}
+	if containsString(f.ExcludeAirlines, flight.Airline) {
+		return false
+	}
+	if containsString(f.ExcludeWeekdays, flight.DayOfWeek) {
+		return false
+	}
+	if !f.Departure.Contains(flight.DepartureTime) {
+		return false
+	}
+	if !f.Arrival.Empty() {
+		arrival, ok := arrivalTime(flight)
+		if !ok || !f.Arrival.Contains(arrival.Format("15:04")) {
+			return false
+		}
+	}
+	return true
+}
+
+// arrivalTime вычисляет местное время прилёта. Без длительности перелёта
+// время прилёта неизвестно.
+func arrivalTime(flight Flight) (time.Time, bool) {
+	if flight.Duration <= 0 {
+		return time.Time{}, false
+	}
+	arrival := flight.DepartureAt.Add(time.Duration(flight.Duration) * time.Minute)
+
+	if airport, ok := mainAirport(flight.Destination); ok {
+		arrival = arrival.In(airport.Location())
+	}
+	return arrival, true
+}
+
+// mainAirport находит аэропорт по коду; для кода города - его основной аэропорт
+func mainAirport(code string) (Airport, bool) {
+	if airport, ok := airports.Airport(code); ok {
+		return airport, true
+	}
+	if city, ok := airports.City(code); ok && len(city.Airports) > 0 {
+		return airports.Airport(city.Airports[0])
+	}
+	return Airport{}, false
+}
+
+// Оценка времени в воздухе: крейсерская скорость и взлёт-посадка на каждом сегменте
+const (
+	cruiseSpeedKmh  = 800
+	segmentOverhead = 30 * time.Minute
+	earthRadiusKm   = 6371.0
+)
+
+// layoverTime оценивает суммарное ожидание на пересадках. Поставщики отдают
+// только общую длительность и число пересадок, поэтому из длительности
+// вычитается время полёта по прямой между городами. Крюк через пересадку
+// засчитывается как ожидание, так что оценка скорее завышена.
+func layoverTime(flight Flight) (time.Duration, bool) {
+	if flight.Duration <= 0 {
+		return 0, false
+	}
+	from, ok := mainAirport(flight.Origin)
+	if !ok {
+		return 0, false
+	}
+	to, ok := mainAirport(flight.Destination)
+	if !ok {
+		return 0, false
+	}
+
+	hours := greatCircleKm(from, to) / cruiseSpeedKmh
+	airborne := time.Duration(hours*float64(time.Hour)) + time.Duration(flight.Transfers+1)*segmentOverhead
+	return max(time.Duration(flight.Duration)*time.Minute-airborne, 0), true
+}
+
+// greatCircleKm - расстояние между аэропортами по поверхности Земли
+func greatCircleKm(from, to Airport) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat, dLon := lat2-lat1, (to.Lon-from.Lon)*math.Pi/180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func (f FareFilters) String() string {
+	var parts []string
+	switch {
+	case f.DirectOnly:
+		parts = append(parts, "только прямые")
+	case f.MaxTransfers > 0:
+		parts = append(parts, fmt.Sprintf("пересадок не больше %d", f.MaxTransfers))
+	}
+	if f.MaxLayover > 0 && !f.DirectOnly {
+		parts = append(parts, "ожидание на пересадках до "+formatDuration(f.MaxLayover))
+	}
+	if len(f.Airlines) > 0 {
+		parts = append(parts, "авиакомпании "+strings.Join(f.Airlines, ", "))
+	}
+	if len(f.ExcludeAirlines) > 0 {
+		parts = append(parts, "кроме "+strings.Join(f.ExcludeAirlines, ", "))
+	}
+	if !f.Departure.Empty() {
+		parts = append(parts, "вылет "+f.Departure.String())
+	}
+	if !f.Arrival.Empty() {
+		parts = append(parts, "прилёт "+f.Arrival.String())
+	}
+	if len(f.ExcludeWeekdays) > 0 {
+		parts = append(parts, "не в "+strings.Join(f.ExcludeWeekdays, ", "))
+	}
+
+	if len(parts) == 0 {
+		return "без фильтров"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fareFilterNames - названия фильтров для настроек и команды /filter
+var fareFilterNames = []string{"transfers", "layover", "airlines", "skip-airlines", "departure", "arrival", "skip-days"}
+
+// isAnyValue - значение, снимающее фильтр
+func isAnyValue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "any", "all", "любые", "все", "-":
+		return true
+	}
+	return false
+}
+
+// Set меняет один фильтр по названию. Значения "any", "любые" или "-" снимают фильтр.
+//
+//	transfers      0 | прямые | 2
+//	layover        4 | 4ч | 2h30m
+//	airlines       SU,S7
+//	skip-airlines  UT,DP
+//	departure      06:00-23:00
+//	arrival        08:00-22:00
+//	skip-days      сб,вс
+func (f *FareFilters) Set(name, value string) error {
+	value = strings.TrimSpace(value)
+	reset := isAnyValue(value)
+
+	switch strings.ToLower(name) {
+	case "transfers":
+		switch lower := strings.ToLower(value); {
+		case reset:
+			f.DirectOnly, f.MaxTransfers = false, 0
+		case lower == "direct" || lower == "прямые":
+			f.DirectOnly, f.MaxTransfers = true, 0
+		default:
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return fmt.Errorf("некорректное число пересадок: %s", value)
+			}
+			f.DirectOnly, f.MaxTransfers = count == 0, count
+		}
+
+	case "layover":
+		if reset {
+			f.MaxLayover = 0
+			break
+		}
+		layover, err := parseLayover(value)
+		if err != nil {
+			return err
+		}
+		f.MaxLayover = layover
+
+	case "airlines", "skip-airlines":
+		var codes []string
+		if !reset {
+			for _, code := range strings.FieldsFunc(value, isListSeparator) {
+				code = strings.ToUpper(code)
+				if len(code) != 2 && len(code) != 3 {
+					return fmt.Errorf("некорректный код авиакомпании: %s", code)
+				}
+				if !containsString(codes, code) {
+					codes = append(codes, code)
+				}
+			}
+		}
+		if strings.ToLower(name) == "airlines" {
+			f.Airlines = codes
+		} else {
+			f.ExcludeAirlines = codes
+		}
+
+	case "departure", "arrival":
+		var window TimeWindow
+		if !reset {
+			parsed, err := ParseTimeWindow(value)
+			if err != nil {
+				return err
+			}
+			window = parsed
+		}
+		if strings.ToLower(name) == "departure" {
+			f.Departure = window
+		} else {
+			f.Arrival = window
+		}
+
+	case "skip-days":
+		var days []string
+		if !reset {
+			for _, name := range strings.FieldsFunc(value, isListSeparator) {
+				day, ok := weekdayNames[strings.ToLower(name)]
+				if !ok {
+					return fmt.Errorf("неизвестный день недели: %s", name)
+				}
+				if !containsString(days, day) {
+					days = append(days, day)
+				}
+			}
+			sort.Slice(days, func(i, j int) bool { return weekdayIndex(days[i]) < weekdayIndex(days[j]) })
+		}
+		f.ExcludeWeekdays = days
+
+	default:
+		return fmt.Errorf("неизвестный фильтр: %s, доступны: %s", name, strings.Join(fareFilterNames, ", "))
+	}
+	return nil
+}
+
+// parseLayover разбирает длительность пересадки в минуты: число - часы, "4ч", "2h30m", "90м"
+func parseLayover(value string) (int, error) {
+	normalized := strings.NewReplacer("ч", "h", "м", "m", " ", "").Replace(strings.ToLower(value))
+	if hours, err := strconv.Atoi(normalized); err == nil {
+		normalized = fmt.Sprintf("%dh", hours)
+	}
+
+	duration, err := time.ParseDuration(normalized)
+	if err != nil || duration < time.Minute {
+		return 0, fmt.Errorf("некорректная длительность пересадки: %s, нужно например 4ч или 2h30m", value)
+	}
+	return int(duration / time.Minute), nil
+}
+
+func isListSeparator(r rune) bool {
+	return r == ',' || r == ' '
+}
+
+// weekdayIndex - номер дня недели начиная с понедельника, для сортировки
+func weekdayIndex(day string) int {
+	for i, name := range []string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"} {
+		if name == day {
+			return i
+		}
+	}
+	return 7
+}
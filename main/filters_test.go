@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testFlight - рейс OVB → AER с вылетом departure ("2006-01-02 15:04", UTC)
+func testFlight(departure string, duration, transfers int, airline string) Flight {
+	departureAt, err := time.Parse("2006-01-02 15:04", departure)
+	if err != nil {
+		panic(err)
+	}
+	return newFlight("OVB", "AER", departureAt, 10000, airline, "https://example.com", duration, transfers)
+}
+
+func TestFareFiltersMatches(t *testing.T) {
+	// 13.11.2026 - пятница, 14.11.2026 - суббота. Сочи живёт по UTC+3.
+	direct := testFlight("2026-11-13 09:30", 300, 0, "S7")
+	oneStop := testFlight("2026-11-13 09:30", 420, 1, "SU")
+	twoStops := testFlight("2026-11-13 09:30", 600, 2, "UT")
+	longLayover := testFlight("2026-11-13 09:30", 1200, 1, "SU")
+	night := testFlight("2026-11-14 22:30", 240, 0, "S7") // прилёт 05:30 по Сочи
+	unknownDuration := testFlight("2026-11-13 09:30", 0, 1, "S7")
+
+	tests := []struct {
+		name    string
+		filters FareFilters
+		flight  Flight
+		want    bool
+	}{
+		{"без фильтров", FareFilters{}, twoStops, true},
+
+		{"только прямые: прямой", FareFilters{DirectOnly: true}, direct, true},
+		{"только прямые: с пересадкой", FareFilters{DirectOnly: true}, oneStop, false},
+		{"до 1 пересадки: одна", FareFilters{MaxTransfers: 1}, oneStop, true},
+		{"до 1 пересадки: две", FareFilters{MaxTransfers: 1}, twoStops, false},
+
+		{"ожидание до 4ч: прямой не ограничен", FareFilters{MaxLayover: 240}, direct, true},
+		{"ожидание до 4ч: короткая стыковка", FareFilters{MaxLayover: 240}, oneStop, true},
+		{"ожидание до 4ч: ночь в пересадке", FareFilters{MaxLayover: 240}, longLayover, false},
+		{"ожидание до 4ч: длительность неизвестна", FareFilters{MaxLayover: 240}, unknownDuration, false},
+
+		{"авиакомпании: из списка", FareFilters{Airlines: []string{"S7", "SU"}}, oneStop, true},
+		{"авиакомпании: не из списка", FareFilters{Airlines: []string{"S7", "SU"}}, twoStops, false},
+		{"кроме авиакомпаний: исключена", FareFilters{ExcludeAirlines: []string{"UT"}}, twoStops, false},
+		{"кроме авиакомпаний: другая", FareFilters{ExcludeAirlines: []string{"UT"}}, direct, true},
+
+		{"вылет: внутри окна", FareFilters{Departure: TimeWindow{"06:00", "12:00"}}, direct, true},
+		{"вылет: граница окна", FareFilters{Departure: TimeWindow{"06:00", "09:30"}}, direct, true},
+		{"вылет: вне окна", FareFilters{Departure: TimeWindow{"12:00", "23:00"}}, direct, false},
+		{"вылет через полночь: поздний вечер", FareFilters{Departure: TimeWindow{"22:00", "06:00"}}, night, true},
+		{"вылет через полночь: днём", FareFilters{Departure: TimeWindow{"22:00", "06:00"}}, direct, false},
+
+		{"прилёт: по местному времени", FareFilters{Arrival: TimeWindow{"05:00", "06:00"}}, night, true},
+		{"прилёт: не по UTC", FareFilters{Arrival: TimeWindow{"02:00", "03:00"}}, night, false},
+		{"прилёт через полночь: под утро", FareFilters{Arrival: TimeWindow{"23:00", "06:00"}}, night, true},
+		{"прилёт через полночь: днём", FareFilters{Arrival: TimeWindow{"23:00", "06:00"}}, direct, false},
+		{"прилёт: длительность неизвестна", FareFilters{Arrival: TimeWindow{"00:00", "23:59"}}, unknownDuration, false},
+
+		{"дни: вылет в исключённый день", FareFilters{ExcludeWeekdays: []string{"Сб", "Вс"}}, night, false},
+		{"дни: вылет в другой день", FareFilters{ExcludeWeekdays: []string{"Сб", "Вс"}}, direct, true},
+
+		{"все фильтры сразу", FareFilters{
+			MaxTransfers:    1,
+			Airlines:        []string{"SU"},
+			Departure:       TimeWindow{"08:00", "10:00"},
+			ExcludeWeekdays: []string{"Сб"},
+		}, oneStop, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filters.Matches(tt.flight); got != tt.want {
+			t.Errorf("%s: Matches = %v, ожидалось %v (%s)", tt.name, got, tt.want, tt.filters)
+		}
+	}
+}
+
+func TestFareFiltersSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  FareFilters
+		err   bool
+	}{
+		{"transfers", "0", FareFilters{DirectOnly: true}, false},
+		{"transfers", "прямые", FareFilters{DirectOnly: true}, false},
+		{"transfers", "2", FareFilters{MaxTransfers: 2}, false},
+		{"transfers", "-1", FareFilters{}, true},
+		{"layover", "4", FareFilters{MaxLayover: 240}, false},
+		{"layover", "2ч30м", FareFilters{MaxLayover: 150}, false},
+		{"layover", "90m", FareFilters{MaxLayover: 90}, false},
+		{"layover", "долго", FareFilters{}, true},
+		{"airlines", "su, s7 SU", FareFilters{Airlines: []string{"SU", "S7"}}, false},
+		{"airlines", "AEROFLOT", FareFilters{}, true},
+		{"skip-airlines", "UT,DP", FareFilters{ExcludeAirlines: []string{"UT", "DP"}}, false},
+		{"departure", "06:00-23:00", FareFilters{Departure: TimeWindow{"06:00", "23:00"}}, false},
+		{"arrival", "22:00 - 06:00", FareFilters{Arrival: TimeWindow{"22:00", "06:00"}}, false},
+		{"departure", "06:00", FareFilters{}, true},
+		{"arrival", "25:00-06:00", FareFilters{}, true},
+		{"skip-days", "вс, пт сб", FareFilters{ExcludeWeekdays: []string{"Пт", "Сб", "Вс"}}, false},
+		{"skip-days", "праздники", FareFilters{}, true},
+		{"price", "10000", FareFilters{}, true},
+	}
+
+	for _, tt := range tests {
+		var filters FareFilters
+		err := filters.Set(tt.name, tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("Set(%s, %q): ошибка %v", tt.name, tt.value, err)
+			continue
+		}
+		if !tt.err && fmt.Sprintf("%+v", filters) != fmt.Sprintf("%+v", tt.want) {
+			t.Errorf("Set(%s, %q) = %+v, ожидалось %+v", tt.name, tt.value, filters, tt.want)
+		}
+	}
+}
+
+func TestFareFiltersSetAnyResets(t *testing.T) {
+	filters := FareFilters{
+		DirectOnly:      true,
+		MaxLayover:      240,
+		Airlines:        []string{"SU"},
+		ExcludeAirlines: []string{"UT"},
+		Departure:       TimeWindow{"06:00", "23:00"},
+		Arrival:         TimeWindow{"08:00", "22:00"},
+		ExcludeWeekdays: []string{"Сб"},
+	}
+	for i, name := range fareFilterNames {
+		if err := filters.Set(name, []string{"any", "любые", "-"}[i%3]); err != nil {
+			t.Fatalf("Set(%s, any): %v", name, err)
+		}
+	}
+	if !filters.Empty() {
+		t.Errorf("после сброса всех фильтров остались %+v", filters)
+	}
+}
+
+func TestSubscriptionQueryUsesChatFilters(t *testing.T) {
+	defaults := SearchQuery{Filters: FareFilters{ExcludeAirlines: []string{"UT"}}}
+	sub := Subscription{ChatID: 1, Origins: []string{"OVB"}, Destination: "AER"}
+
+	if query := sub.Query(defaults, ChatSettings{}); fmt.Sprint(query.Filters.ExcludeAirlines) != "[UT]" {
+		t.Errorf("без настроек чата ожидались фильтры по умолчанию, получено %+v", query.Filters)
+	}
+
+	chat := FareFilters{DirectOnly: true}
+	query := sub.Query(defaults, ChatSettings{Filters: &chat})
+	if !query.Filters.DirectOnly || len(query.Filters.ExcludeAirlines) != 0 {
+		t.Errorf("фильтры чата должны заменять фильтры по умолчанию, получено %+v", query.Filters)
+	}
+}
+
+func TestFilterCommandChangesOnlyItsChat(t *testing.T) {
+	bot, _ := newTestBot(t, &AppConfig{DestinationIATA: "AER", Filters: FareFilters{ExcludeAirlines: []string{"UT"}}})
+
+	filterHandler("transfers")(bot, testMessage(1, 1, "private"), []string{"0"})
+	filterHandler("layover")(bot, testMessage(1, 1, "private"), []string{"3ч"})
+
+	got := bot.chatQuery(1).Filters
+	if !got.DirectOnly || got.MaxLayover != 180 || fmt.Sprint(got.ExcludeAirlines) != "[UT]" {
+		t.Errorf("фильтры чата 1: %+v", got)
+	}
+	if other := bot.chatQuery(2).Filters; other.DirectOnly || other.MaxLayover != 0 {
+		t.Errorf("фильтры чата 1 попали в чат 2: %+v", other)
+	}
+	if defaults := bot.flightSearch.DefaultQuery().Filters; defaults.DirectOnly {
+		t.Errorf("фильтры по умолчанию изменились: %+v", defaults)
+	}
+
+	bot.handleFilterReset(testMessage(1, 1, "private"), nil)
+	if got := bot.chatQuery(1).Filters; got.DirectOnly || fmt.Sprint(got.ExcludeAirlines) != "[UT]" {
+		t.Errorf("после сброса ожидались фильтры по умолчанию, получено %+v", got)
+	}
+}
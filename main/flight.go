@@ -1,87 +1,20 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-var CityAirports = map[string][]string{
-	// Азия
-	"бали":         {"DPS"},
-	"денпасар":     {"DPS"},
-	"бангкок":      {"BKK"},
-	"пхукет":       {"HKT"},
-	"сингапур":     {"SIN"},
-	"куалалумпур":  {"KUL"},
-	"куала-лумпур": {"KUL"},
-	"ханой":        {"HAN"},
-	"хошимин":      {"SGN"},
-	"дананг":       {"DAD"},
-	"той":          {"NRT", "HND"},
-	"сеул":         {"ICN", "GMP"},
-	"пекин":        {"PEK"},
-	"шахай":        {"PVG"},
-	"шанхай":       {"PVG"},
-	"дел":          {"DEL"},
-	"дуба":         {"DXB"},
-	"дубай":        {"DXB"},
-	"стамбул":      {"IST"},
-
-	// Европа
-	"франкфурт": {"FRA"},
-	"париж":     {"CDG", "ORY"},
-	"лондон":    {"LHR", "LGW", "STN"},
-	"берлин":    {"BER", "SXF", "TXL"},
-	"амстердам": {"AMS"},
-	"праж":      {"PRG"},
-	"прага":     {"PRG"},
-	"рим":       {"FCO"},
-	"милан":     {"MXP", "LIN"},
-	"мадрид":    {"MAD"},
-	"барселон":  {"BCN"},
-	"барселона": {"BCN"},
-	"вена":      {"VIE"},
-	"варшав":    {"WAW"},
-	"варшава":   {"WAW"},
-
-	// Америка
-	"ньюйорк":     {"JFK", "LGA", "EWR"},
-	"нью-йорк":    {"JFK", "LGA", "EWR"},
-	"лосанделес":  {"LAX"},
-	"лос-анделес": {"LAX"},
-	"маям":        {"MIA"},
-	"майами":      {"MIA"},
-	"чикаг":       {"ORD", "MDW"},
-	"чикаго":      {"ORD", "MDW"},
-	"торонт":      {"YYZ"},
-	"торонто":     {"YYZ"},
-	"вancouver":   {"YVR"},
-	"ванкувер":    {"YVR"},
-
-	// Россия и СНГ
-	"москв":          {"SVO", "DME", "VKO"},
-	"москва":         {"SVO", "DME", "VKO"},
-	"санктпетербург": {"LED"},
-	"петербург":      {"LED"},
-	"екатеринбург":   {"SVX"},
-	"красноярск":     {"KJA"},
-	"иркутск":        {"IKT"},
-	"владивосток":    {"VVO"},
-	"хабаровск":      {"KHV"},
-	"алмат":          {"ALA"},
-	"алматы":         {"ALA"},
-	"ташкент":        {"TAS"},
-	"бишкек":         {"FRU"},
-}
-
 type Flight struct {
 	Origin        string
 	Destination   string
+	DepartureAt   time.Time
 	DepartureDate string
 	DayOfWeek     string
 	DepartureTime string
@@ -92,184 +25,647 @@ type Flight struct {
 	Transfers     int
 }
 
-type APIResponse struct {
-	Data []struct {
-		Origin      string `json:"origin"`
-		Destination string `json:"destination"`
-		DepartureAt string `json:"departure_at"`
-		Price       int    `json:"price"`
-		Airline     string `json:"airline"`
-		Link        string `json:"link"`
-		Duration    int    `json:"duration"`
-		Transfers   int    `json:"transfers"`
-	} `json:"data"`
-	Error   string `json:"error"`
-	Success bool   `json:"success"`
+// newFlight заполняет производные поля рейса (дата, день недели, время вылета)
+func newFlight(origin, destination string, departureAt time.Time, price int, airline, link string, duration, transfers int) Flight {
+	return Flight{
+		Origin:        origin,
+		Destination:   destination,
+		DepartureAt:   departureAt,
+		DepartureDate: departureAt.Format("02.01.2006"),
+		DayOfWeek:     getRussianDayOfWeek(departureAt.Weekday()),
+		DepartureTime: departureAt.Format("15:04"),
+		Price:         price,
+		Airline:       airline,
+		Link:          link,
+		Duration:      duration,
+		Transfers:     transfers,
+	}
+}
+
+// searchTimeout ограничивает время одного поиска
+const searchTimeout = 2 * time.Minute
+
+// SearchQuery - параметры одного поиска. Передается по значению,
+// поэтому поиск не зависит от последующих изменений конфигурации.
+type SearchQuery struct {
+	Origins        []string
+	Destination    string
+	MonthsToSearch int
+	MaxPrice       int
+	MaxFlightTime  int
+	DateFilter     DateFilter
+	Filters        FareFilters // Пересадки, авиакомпании, время и дни вылета
+
+	// Режим туда-обратно: рейсы объединяются в поездки длительностью
+	// от MinNights до MaxNights ночей, MaxPrice ограничивает цену всей поездки
+	RoundTrip bool
+	MinNights int
+	MaxNights int
+}
+
+// LegError - ошибка поставщика по одному плечу поиска (направление + месяц)
+type LegError struct {
+	Provider    string
+	Origin      string
+	Destination string
+	Month       string
+	Err         error
+}
+
+func (e LegError) Error() string {
+	return fmt.Sprintf("%s → %s (%s): %v", e.Origin, e.Destination, e.Month, e.Err)
+}
+
+// Description возвращает понятную пользователю причину сбоя
+func (e LegError) Description() string {
+	var providerErr *ProviderError
+	if errors.As(e.Err, &providerErr) {
+		return providerErr.Description()
+	}
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return "превышено время ожидания"
+	}
+	return e.Err.Error()
+}
+
+// Permanent сообщает, что ошибка не исчезнет при повторе (например, неверный токен)
+func (e LegError) Permanent() bool {
+	return !isRetryable(e.Err)
+}
+
+// SearchResult - структурированный результат поиска
+type SearchResult struct {
+	Query       SearchQuery
+	Months      []string
+	Outbound    []Flight    // Рейсы из городов вылета в пункт назначения
+	Return      []Flight    // Обратные рейсы
+	Itineraries []Itinerary // Поездки туда-обратно, только при Query.RoundTrip
+	Errors      []LegError
+	StartedAt   time.Time
+	Duration    time.Duration
+}
+
+// Empty сообщает, что не найдено ни одного рейса
+func (r *SearchResult) Empty() bool {
+	if r.Query.RoundTrip {
+		return len(r.Itineraries) == 0
+	}
+	return len(r.Outbound) == 0 && len(r.Return) == 0
+}
+
+// Clone возвращает копию запроса, не разделяющую срезы с оригиналом
+func (q SearchQuery) Clone() SearchQuery {
+	clone := q
+	clone.Origins = append([]string(nil), q.Origins...)
+	clone.DateFilter.Dates = append([]string(nil), q.DateFilter.Dates...)
+	clone.Filters = q.Filters.Clone()
+	return clone
 }
 
+// FlightSearch выполняет поиск по переданному запросу. Параметры по умолчанию
+// читаются из конфигурации один раз и дальше изменяются только под блокировкой,
+// поэтому бот и планировщик могут работать с сервисом параллельно.
 type FlightSearch struct {
-	config *AppConfig
+	providers []FareProvider
+	history   *PriceHistory
+	workers   int
+
+	mu       sync.RWMutex
+	defaults SearchQuery
 }
 
+// FindAirportCode ищет аэропорты города по названию или коду и возвращает
+// лучшего кандидата нечёткого поиска
 func FindAirportCode(cityName string) ([]string, string) {
-	// Приводим к нижнему регистру и убираем пробелы
-	normalized := strings.ToLower(strings.TrimSpace(cityName))
+	matches := airports.SearchCities(cityName)
+	if len(matches) == 0 {
+		return nil, ""
+	}
 
-	// Прямой поиск
-	if codes, exists := CityAirports[normalized]; exists {
-		return codes, getCityName(codes[0])
+	if len(matches[0].Codes) == 1 {
+		return matches[0].Codes, getCityName(matches[0].Codes[0])
 	}
+	return matches[0].Codes, matches[0].City.NameRu
+}
 
-	// Поиск по частичному совпадению
-	for city, codes := range CityAirports {
-		if strings.Contains(normalized, city) || strings.Contains(city, normalized) {
-			return codes, getCityName(codes[0])
+// GetCityList возвращает список городов справочника с кодами аэропортов,
+// сгруппированный по странам. Пустой country - все страны.
+func GetCityList(country string) string {
+	cities := airports.Cities()
+	if country != "" {
+		cities = airports.CitiesInCountry(country)
+	}
+
+	var countries []string
+	byCountry := make(map[string][]string)
+	for _, city := range cities {
+		if _, exists := byCountry[city.CountryRu]; !exists {
+			countries = append(countries, city.CountryRu)
 		}
+		byCountry[city.CountryRu] = append(byCountry[city.CountryRu],
+			fmt.Sprintf("%s - %s (%s)", city.Code, city.NameRu, strings.Join(city.Airports, ", ")))
 	}
+	sort.Strings(countries)
 
-	return nil, ""
+	var sb strings.Builder
+	for _, name := range countries {
+		sb.WriteString(fmt.Sprintf("<b>%s</b>\n%s\n\n", name, strings.Join(byCountry[name], "\n")))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// NewFlightSearch создает поисковый сервис. history может быть nil,
+// тогда наблюдённые цены не сохраняются.
+func NewFlightSearch(config *AppConfig, providers []FareProvider, history *PriceHistory) *FlightSearch {
+	defaults := SearchQuery{
+		Origins:        config.OriginIATA,
+		Destination:    config.DestinationIATA,
+		MonthsToSearch: config.MonthsToSearch,
+		MaxPrice:       config.MaxPrice,
+		MaxFlightTime:  config.MaxFlightTime,
+		DateFilter:     config.DateFilter,
+		Filters:        config.Filters,
+		RoundTrip:      config.RoundTrip,
+		MinNights:      config.MinNights,
+		MaxNights:      config.MaxNights,
+	}
+
+	return &FlightSearch{
+		providers: providers,
+		history:   history,
+		workers:   config.SearchWorkers,
+		defaults:  defaults.Clone(),
+	}
 }
 
-func GetCityList() string {
-	var cities []string
+// DefaultQuery возвращает копию параметров поиска по умолчанию
+func (fs *FlightSearch) DefaultQuery() SearchQuery {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
 
-	// Собираем уникальные города (берем первый аэропорт для каждого города)
-	addedCities := make(map[string]bool)
-	for _, codes := range CityAirports {
-		if len(codes) > 0 && !addedCities[codes[0]] {
-			cityName := getCityName(codes[0])
-			cities = append(cities, fmt.Sprintf("%s - %s", codes[0], cityName))
-			addedCities[codes[0]] = true
+	return fs.defaults.Clone()
+}
+
+// Search выполняет поиск по всем направлениям и месяцам запроса.
+// Запросы к поставщикам выполняются параллельно ограниченным пулом воркеров;
+// при отмене ctx поиск прерывается и возвращается ошибка контекста.
+func (fs *FlightSearch) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	result := &SearchResult{
+		Query:     query.Clone(),
+		StartedAt: time.Now(),
+	}
+	fmt.Printf("\n%s Начинаем поиск билетов...\n", result.StartedAt.Format("2006-01-02 15:04"))
+
+	query = result.Query
+	if len(query.Origins) == 0 || query.Destination == "" {
+		return nil, fmt.Errorf("не задан маршрут поиска")
+	}
+
+	months, err := query.months(result.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	result.Months = months
+
+	// Города раскрываются во все их аэропорты: Москва - SVO, DME, VKO, ZIA
+	var origins []string
+	for _, origin := range query.Origins {
+		origins = append(origins, airports.ExpandCode(origin)...)
+	}
+	destinations := airports.ExpandCode(query.Destination)
+
+	// В режиме туда-обратно обратный рейс нужен в каждый город вылета
+	// и может прийтись на месяц после последнего месяца поиска
+	returnTargets := airports.ExpandCode(query.Origins[0])
+	returnMonths := result.Months
+	if query.RoundTrip {
+		returnTargets = origins
+		returnMonths = withFollowingMonths(result.Months)
+	}
+
+	var legs []searchLeg
+	for _, month := range result.Months {
+		for _, origin := range origins {
+			for _, destination := range destinations {
+				legs = append(legs, fs.legs(query, origin, destination, month, false)...)
+			}
 		}
 	}
+	for _, month := range returnMonths {
+		for _, destination := range destinations {
+			for _, target := range returnTargets {
+				legs = append(legs, fs.legs(query, destination, target, month, true)...)
+			}
+		}
+	}
+
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("нет запросов для поиска: не настроены поставщики цен или направления")
+	}
 
-	sort.Strings(cities)
-	return strings.Join(cities, "\n")
+	for _, leg := range fs.runLegs(ctx, legs, result.StartedAt) {
+		if leg.err != nil {
+			result.Errors = append(result.Errors, LegError{
+				Provider:    leg.provider.Name(),
+				Origin:      leg.origin,
+				Destination: leg.destination,
+				Month:       leg.month,
+				Err:         leg.err,
+			})
+			continue
+		}
+
+		for _, flight := range leg.flights {
+			if !query.matchesLeg(flight, leg.back) {
+				continue
+			}
+			if leg.back {
+				result.Return = append(result.Return, flight)
+			} else {
+				result.Outbound = append(result.Outbound, flight)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) == len(legs) {
+		return nil, fmt.Errorf("ни один запрос к поставщикам не выполнен: %w", result.Errors[0].Err)
+	}
+
+	result.Outbound = dedupeFlights(result.Outbound)
+	result.Return = dedupeFlights(result.Return)
+	if query.RoundTrip {
+		result.Itineraries = BuildItineraries(result.Outbound, result.Return, query.MinNights, query.MaxNights, query.MaxPrice)
+	}
+	result.Duration = time.Since(result.StartedAt)
+	return result, nil
 }
 
-func NewFlightSearch(config *AppConfig) *FlightSearch {
-	return &FlightSearch{
-		config: config,
+// searchMonths возвращает месяцы поиска в формате "2006-01", начиная с текущего
+// и на count вперёд. Переход через границу года учитывается автоматически.
+func searchMonths(now time.Time, count int) []string {
+	if count < 1 {
+		count = 1
+	}
+
+	months := make([]string, 0, count)
+	for monthOffset := 0; monthOffset < count; monthOffset++ {
+		monthDate := time.Date(now.Year(), now.Month()+time.Month(monthOffset), 1, 0, 0, 0, 0, time.Local)
+		months = append(months, monthDate.Format("2006-01"))
 	}
+	return months
 }
 
-func (fs *FlightSearch) Search() (string, error) {
-	fmt.Printf("\n%s Начинаем поиск билетов...\n", time.Now().Format("2006-01-02 15:04"))
+// searchLeg - один запрос к поставщику: направление, месяц и его результат
+type searchLeg struct {
+	provider    FareProvider
+	origin      string
+	destination string
+	month       string
+	back        bool
+	direct      bool
+	explore     bool // Поиск направлений: destination пустой
+
+	flights []Flight
+	err     error
+}
 
-	var arrival []Flight
-	var departure []Flight
+func (fs *FlightSearch) legs(query SearchQuery, origin, destination, month string, back bool) []searchLeg {
+	legs := make([]searchLeg, 0, len(fs.providers))
+	for _, provider := range fs.providers {
+		legs = append(legs, searchLeg{
+			provider:    provider,
+			origin:      origin,
+			destination: destination,
+			month:       month,
+			back:        back,
+			direct:      query.Filters.DirectOnly,
+		})
+	}
+	return legs
+}
 
-	for _, origin := range fs.config.OriginIATA {
-		flights := fs.searchFlightsForOrigin(origin, 3, false)
-		arrival = append(arrival, flights...)
+// runLegs выполняет запросы пулом из fs.workers воркеров. Частоту обращений
+// к поставщику ограничивает его собственный лимитер, общий для всех воркеров.
+func (fs *FlightSearch) runLegs(ctx context.Context, legs []searchLeg, observedAt time.Time) []searchLeg {
+	workers := fs.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(legs) {
+		workers = len(legs)
 	}
 
-	departure = append(departure, fs.searchFlightsForOrigin(fs.config.DestinationIATA, 3, true)...)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				leg := &legs[i]
+				if err := ctx.Err(); err != nil {
+					leg.err = err
+					continue
+				}
+
+				fmt.Printf("Проверяем %s -> %s на %s (%s)...\n", leg.origin, leg.destination, leg.month, leg.provider.Name())
+
+				query := FareQuery{
+					Origin:      leg.origin,
+					Destination: leg.destination,
+					Month:       leg.month,
+					Currency:    "rub",
+					Direct:      leg.direct,
+				}
+				if leg.explore {
+					// Цены направлений берутся из кэша поставщика и в историю не пишутся
+					leg.flights, leg.err = exploreFares(ctx, leg.provider, query)
+					continue
+				}
+
+				leg.flights, leg.err = leg.provider.SearchFares(ctx, query)
+				if leg.err != nil {
+					fmt.Printf("Ошибка поставщика: %v\n", leg.err)
+					continue
+				}
+
+				fs.recordHistory(leg.flights, observedAt)
+			}
+		}()
+	}
 
-	if len(arrival) > 0 || len(departure) > 0 {
-		return fs.formatMessage(arrival, departure), nil
+	for i := range legs {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
-	return "ℹ️ Дешёвых билетов не найдено.", nil
+	return legs
 }
 
-func (fs *FlightSearch) searchFlightsForOrigin(origin string, month int, backTicket bool) []Flight {
-	var flights []Flight
-	var dest string
-	if backTicket {
-		dest = fs.config.OriginIATA[0]
-	} else {
-		dest = fs.config.DestinationIATA
+// recordHistory сохраняет все полученные от поставщика цены, в том числе
+// не прошедшие фильтры запроса: история нужна для оценки динамики цен
+func (fs *FlightSearch) recordHistory(flights []Flight, observedAt time.Time) {
+	if fs.history == nil {
+		return
 	}
+	if err := fs.history.Record(dedupeFlights(flights), observedAt); err != nil {
+		fmt.Printf("Ошибка сохранения истории цен: %v\n", err)
+	}
+}
 
-	for monthOffset := 0; monthOffset < 1; monthOffset++ {
-		currentYear := time.Now().Year()
-		monthDate := time.Date(currentYear, time.Month(month), 1, 0, 0, 0, 0, time.Local)
-		monthStr := monthDate.Format("2006-01")
+// Matches проверяет рейс на соответствие ограничениям запроса
+func (q SearchQuery) Matches(flight Flight) bool {
+	if q.MaxPrice > 0 && flight.Price > q.MaxPrice {
+		return false
+	}
+	if q.MaxFlightTime > 0 && flight.Duration > q.MaxFlightTime {
+		return false
+	}
+	if !q.Filters.Matches(flight) {
+		return false
+	}
+	return q.DateFilter.Matches(flight.DepartureAt.Format(time.RFC3339))
+}
 
-		fmt.Printf("Проверяем %s -> %s на %s...\n", origin, dest, monthStr)
+// matchesLeg применяет ограничения запроса к отдельному рейсу. В режиме
+// туда-обратно цена проверяется для поездки целиком, а фильтр дат -
+// только для вылета туда.
+func (q SearchQuery) matchesLeg(flight Flight, back bool) bool {
+	if !q.RoundTrip {
+		return q.Matches(flight)
+	}
+	if q.MaxFlightTime > 0 && flight.Duration > q.MaxFlightTime {
+		return false
+	}
+	if !q.Filters.Matches(flight) {
+		return false
+	}
+	if q.MaxPrice > 0 && flight.Price > q.MaxPrice {
+		return false
+	}
+	return back || q.DateFilter.Matches(flight.DepartureAt.Format(time.RFC3339))
+}
 
-		apiURL := fs.config.TravelPayoutsUrlPrice
+// dedupeFlights убирает одинаковые рейсы, пришедшие от разных поставщиков
+// или из пересекающихся запросов, оставляя самое дешёвое предложение
+func dedupeFlights(flights []Flight) []Flight {
+	index := make(map[string]int, len(flights))
+	result := make([]Flight, 0, len(flights))
 
-		params := url.Values{}
-		params.Add("origin", origin)
-		params.Add("destination", dest)
-		params.Add("currency", "rub")
-		params.Add("departure_at", monthStr)
-		params.Add("sorting", "price")
-		params.Add("direct", "false")
-		params.Add("limit", "30")
-		params.Add("one_way", "true")
-		params.Add("token", fs.config.TravelPayoutsToken)
+	for _, flight := range flights {
+		key := fmt.Sprintf("%s|%s|%s|%s", flight.Origin, flight.Destination,
+			flight.DepartureAt.Format(time.RFC3339), flight.Airline)
 
-		req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
-		if err != nil {
-			fmt.Printf("Ошибка создания запроса: %v\n", err)
+		if i, exists := index[key]; exists {
+			if flight.Price < result[i].Price {
+				result[i] = flight
+			}
 			continue
 		}
 
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-		req.Header.Set("Accept", "application/json")
+		index[key] = len(result)
+		result = append(result, flight)
+	}
 
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
+	return result
+}
+
+// ParseDateFilter разбирает фильтр дат из аргумента команды:
+// "2026-03-01..2026-03-20" - диапазон, "2026-03-01,2026-03-05" - список дат,
+// "2026-03-10±3" - желаемая дата с отклонением в днях
+func ParseDateFilter(arg string) (DateFilter, error) {
+	if target, days, isFlex := cutFlex(arg); isFlex {
+		return parseFlexDate(target, days)
+	}
+
+	if from, to, isRange := strings.Cut(arg, ".."); isRange {
+		startDate, err := time.Parse("2006-01-02", strings.TrimSpace(from))
 		if err != nil {
-			fmt.Printf("Ошибка сети: %v\n", err)
-			continue
+			return DateFilter{}, fmt.Errorf("некорректная дата начала: %s", from)
+		}
+		endDate, err := time.Parse("2006-01-02", strings.TrimSpace(to))
+		if err != nil {
+			return DateFilter{}, fmt.Errorf("некорректная дата окончания: %s", to)
 		}
-		defer resp.Body.Close()
+		if endDate.Before(startDate) {
+			return DateFilter{}, fmt.Errorf("дата окончания раньше даты начала")
+		}
+		return DateFilter{StartDate: startDate, EndDate: endDate, Enabled: true, Mode: "range"}, nil
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("HTTP ошибка: %s\n", resp.Status)
-			continue
+	filter := DateFilter{Enabled: true, Mode: "list"}
+	for _, dateStr := range strings.Split(arg, ",") {
+		dateStr = strings.TrimSpace(dateStr)
+		if _, err := time.Parse("2006-01-02", dateStr); err != nil {
+			return DateFilter{}, fmt.Errorf("некорректная дата: %s", dateStr)
 		}
+		filter.Dates = append(filter.Dates, dateStr)
+	}
+	return filter, nil
+}
 
-		var apiResponse APIResponse
-		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-			fmt.Printf("Ошибка парсинга JSON: %v\n", err)
-			continue
+// maxMonthsToSearch - на сколько месяцев вперёд поставщики отдают цены
+const maxMonthsToSearch = 12
+
+// ParseMonths разбирает глубину поиска в месяцах: целое число от 1 до 12
+func ParseMonths(arg string) (int, error) {
+	months, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || months < 1 || months > maxMonthsToSearch {
+		return 0, fmt.Errorf("некорректное число месяцев: %s, нужно от 1 до %d", arg, maxMonthsToSearch)
+	}
+	return months, nil
+}
+
+// maxFlexDays - наибольшее отклонение от желаемой даты в режиме "flex"
+const maxFlexDays = 7
+
+// cutFlex разделяет гибкую дату "2026-12-20±3" (или "2026-12-20+-3") на дату и отклонение
+func cutFlex(arg string) (string, string, bool) {
+	for _, sep := range []string{"±", "+-"} {
+		if target, days, found := strings.Cut(arg, sep); found {
+			return target, days, true
 		}
+	}
+	return "", "", false
+}
 
-		if !apiResponse.Success {
-			fmt.Printf("API ошибка: %s\n", apiResponse.Error)
-			continue
+func parseFlexDate(targetStr string, daysStr string) (DateFilter, error) {
+	target, err := time.Parse("2006-01-02", strings.TrimSpace(targetStr))
+	if err != nil {
+		return DateFilter{}, fmt.Errorf("некорректная дата: %s", targetStr)
+	}
+	days, err := strconv.Atoi(strings.TrimSpace(daysStr))
+	if err != nil || days < 0 || days > maxFlexDays {
+		return DateFilter{}, fmt.Errorf("некорректное отклонение: %s, нужно от 0 до %d дней", daysStr, maxFlexDays)
+	}
+
+	return DateFilter{
+		StartDate: target.AddDate(0, 0, -days),
+		EndDate:   target.AddDate(0, 0, days),
+		Target:    target,
+		FlexDays:  days,
+		Enabled:   true,
+		Mode:      "flex",
+	}, nil
+}
+
+// Months возвращает месяцы "2006-01", в которые попадают даты фильтра, начиная
+// с месяца now. bounded = false, если фильтр не ограничивает даты сверху и
+// глубину поиска задает MonthsToSearch.
+func (df DateFilter) Months(now time.Time) (months []string, bounded bool) {
+	if !df.Enabled {
+		return nil, false
+	}
+
+	current := now.Format("2006-01")
+	add := func(date time.Time) {
+		month := date.Format("2006-01")
+		if month >= current && !containsString(months, month) {
+			months = append(months, month)
 		}
+	}
 
-		for _, flightData := range apiResponse.Data {
-			if flightData.Price > fs.config.MaxPrice {
-				continue
-			}
-			if flightData.Duration > fs.config.MaxFlightTime {
-				continue
-			}
-			if !fs.config.DateFilter.Matches(flightData.DepartureAt) {
-				continue
-			}
-			departureTime, err := time.Parse(time.RFC3339, flightData.DepartureAt)
-			if err != nil {
-				fmt.Printf("Ошибка парсинга даты: %v\n", err)
-				continue
+	switch df.Mode {
+	case "range", "flex":
+		if df.EndDate.IsZero() {
+			return nil, false
+		}
+		start := df.StartDate
+		if start.IsZero() || start.Before(now) {
+			start = now
+		}
+		for month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !month.After(df.EndDate); month = month.AddDate(0, 1, 0) {
+			add(month)
+		}
+	case "list":
+		for _, dateStr := range df.Dates {
+			if date, err := time.Parse("2006-01-02", dateStr); err == nil {
+				add(date)
 			}
+		}
+		sort.Strings(months)
+	default:
+		return nil, false
+	}
+	return months, true
+}
 
-			flight := Flight{
-				Origin:        origin,
-				Destination:   flightData.Destination,
-				DepartureDate: departureTime.Format("02.01.2006"),
-				DayOfWeek:     getRussianDayOfWeek(departureTime.Weekday()),
-				DepartureTime: departureTime.Format("15:04"),
-				Price:         flightData.Price,
-				Airline:       flightData.Airline,
-				Link:          "https://aviasales.ru" + flightData.Link,
-				Duration:      flightData.Duration,
-				Transfers:     flightData.Transfers,
-			}
-			flights = append(flights, flight)
+// months возвращает месяцы поиска запроса: по фильтру дат, если он ограничен,
+// иначе MonthsToSearch месяцев от текущего
+func (q SearchQuery) months(now time.Time) ([]string, error) {
+	months, bounded := q.DateFilter.Months(now)
+	if !bounded {
+		return searchMonths(now, q.MonthsToSearch), nil
+	}
+	if len(months) == 0 {
+		return nil, fmt.Errorf("даты поиска (%s) уже прошли", q.DateFilter.String())
+	}
+	return months, nil
+}
+
+// withFollowingMonths дополняет месяцы "2006-01" следующими за каждым из них,
+// куда может прийтись обратный рейс
+func withFollowingMonths(months []string) []string {
+	result := append([]string(nil), months...)
+	for _, month := range months {
+		date, err := time.Parse("2006-01", month)
+		if err != nil {
+			continue
+		}
+		if next := date.AddDate(0, 1, 0).Format("2006-01"); !containsString(result, next) {
+			result = append(result, next)
 		}
+	}
+	sort.Strings(result)
+	return result
+}
 
-		time.Sleep(1 * time.Second)
+// ParseNightsRange разбирает длительность поездки в ночах: "7-14" или "10"
+func ParseNightsRange(arg string) (int, int, error) {
+	from, to, isRange := strings.Cut(arg, "-")
+	if !isRange {
+		to = from
 	}
 
-	return flights
+	minNights, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || minNights < 0 {
+		return 0, 0, fmt.Errorf("некорректное число ночей: %s", arg)
+	}
+	maxNights, err := strconv.Atoi(strings.TrimSpace(to))
+	if err != nil || maxNights < minNights {
+		return 0, 0, fmt.Errorf("некорректное число ночей: %s", arg)
+	}
+	return minNights, maxNights, nil
+}
+
+// String возвращает описание фильтра для пользователя
+func (df DateFilter) String() string {
+	if !df.Enabled {
+		return "любые даты"
+	}
+
+	switch df.Mode {
+	case "flex":
+		return fmt.Sprintf("%s ±%d дн.", df.Target.Format("02.01.2006"), df.FlexDays)
+	case "range":
+		from, to := "…", "…"
+		if !df.StartDate.IsZero() {
+			from = df.StartDate.Format("02.01.2006")
+		}
+		if !df.EndDate.IsZero() {
+			to = df.EndDate.Format("02.01.2006")
+		}
+		return from + " – " + to
+	case "list":
+		return strings.Join(df.Dates, ", ")
+	default:
+		return "любые даты"
+	}
 }
 
 func (df *DateFilter) Matches(dateStr string) bool {
@@ -283,7 +679,7 @@ func (df *DateFilter) Matches(dateStr string) bool {
 	}
 
 	switch df.Mode {
-	case "range":
+	case "range", "flex":
 		// Проверяем, попадает ли дата в диапазон
 		if !df.StartDate.IsZero() && flightDate.Before(df.StartDate) {
 			return false
@@ -307,164 +703,18 @@ func (df *DateFilter) Matches(dateStr string) bool {
 	}
 }
 
-func (fs *FlightSearch) formatMessage(arrival []Flight, departure []Flight) string {
-	var sb strings.Builder
-
-	sb.WriteString("✈️ <b>НАЙДЕНЫ ДЕШЁВЫЕ БИЛЕТЫ!</b>\n\n")
-
-	arrivalByOrigin := make(map[string][]Flight)
-	for _, flight := range arrival {
-		arrivalByOrigin[flight.Origin] = append(arrivalByOrigin[flight.Origin], flight)
-	}
-
-	for origin, originFlights := range arrivalByOrigin {
-		sort.Slice(originFlights, func(i, j int) bool {
-			return originFlights[i].Price < originFlights[j].Price
-		})
-
-		cityName := getCityName(origin)
-		destName := getCityName(fs.config.DestinationIATA)
-
-		sb.WriteString(fmt.Sprintf("🛫 <b>%s → %s</b>\n", cityName, destName))
-		sb.WriteString("<code>")
-		sb.WriteString("Дата          | Цена    | Время   | Пересад | Рейс\n")
-		sb.WriteString("--------------|---------|---------|---------|------\n")
-		sb.WriteString("</code>")
-
-		for _, flight := range originFlights[:min(10, len(originFlights))] {
-			transfersStr := getTransfersText(flight.Transfers)
-
-			sb.WriteString(fmt.Sprintf(
-				"<code>%s %s | %6d₽ | %s | %7s | %s</code> ",
-				flight.DepartureDate,
-				flight.DayOfWeek,
-				flight.Price,
-				formatDuration(flight.Duration),
-				transfersStr,
-				flight.Airline,
-			))
-			sb.WriteString(fmt.Sprintf("<a href='%s'>🎫</a>\n", flight.Link))
-		}
-		sb.WriteString("\n")
-	}
-
-	//todo дубликат код вынеси в отдельный метод
-	departureByOrigin := make(map[string][]Flight)
-	for _, flight := range departure {
-		departureByOrigin[flight.Origin] = append(departureByOrigin[flight.Origin], flight)
-	}
-
-	for origin, departureFlights := range departureByOrigin {
-		sort.Slice(departureFlights, func(i, j int) bool {
-			return departureFlights[i].Price < departureFlights[j].Price
-		})
-
-		cityName := getCityName(origin)
-		destName := getCityName(fs.config.OriginIATA[0])
-
-		sb.WriteString(fmt.Sprintf("🛫 <b>%s → %s</b>\n", cityName, destName))
-		sb.WriteString("<code>")
-		sb.WriteString("Дата          | Цена    | Время   | Пересад | Рейс\n")
-		sb.WriteString("--------------|---------|---------|---------|------\n")
-		sb.WriteString("</code>")
-
-		for _, flight := range departureFlights[:min(10, len(departureFlights))] {
-			transfersStr := getTransfersText(flight.Transfers)
-
-			sb.WriteString(fmt.Sprintf(
-				"<code>%s %s | %6d₽ | %s | %7s | %s</code> ",
-				flight.DepartureDate,
-				flight.DayOfWeek,
-				flight.Price,
-				formatDuration(flight.Duration),
-				transfersStr,
-				flight.Airline,
-			))
-			sb.WriteString(fmt.Sprintf("<a href='%s'>🎫</a>\n", flight.Link))
-		}
-		sb.WriteString("\n")
-	}
-
-	sb.WriteString("📊 <b>Информация:</b>\n")
-	sb.WriteString("   • 🎫 - ссылка на покупку\n")
-
-	return sb.String()
-}
-
 // Вспомогательные функции
 func getCityName(iata string) string {
-	cityNames := map[string]string{
-		// Азия
-		"DPS": "Денпасар (Бали)",
-		"BKK": "Бангкок",
-		"HKT": "Пхукет",
-		"SYD": "Сидней",
-		"AKL": "Окленд",
-		"SIN": "Сингапур",
-		"KUL": "Куала-Лумпур",
-		"HAN": "Ханой",
-		"SGN": "Хошимин",
-		"NRT": "Токио (Наррита)",
-		"HND": "Токио (Ханеда)",
-		"ICN": "Сеул",
-		"GMP": "Сеул (Кимхо)",
-		"PEK": "Пекин",
-		"PVG": "Шанхай",
-		"DEL": "Дели",
-		"DXB": "Дубай",
-		"IST": "Стамбул",
-
-		// Европа
-		"FRA": "Франкфурт",
-		"CDG": "Париж (Шарль-де-Голль)",
-		"ORY": "Париж (Орли)",
-		"LHR": "Лондон (Хитроу)",
-		"LGW": "Лондон (Гатвик)",
-		"STN": "Лондон (Станстед)",
-		"BER": "Берлин",
-		"AMS": "Амстердам",
-		"PRG": "Прага",
-		"FCO": "Рим",
-		"MXP": "Милан",
-		"MAD": "Мадрид",
-		"BCN": "Барселона",
-		"VIE": "Вена",
-		"WAW": "Варшава",
-
-		// Америка
-		"JFK": "Нью-Йорк (Кеннеди)",
-		"LGA": "Нью-Йорк (ЛаГуардиа)",
-		"EWR": "Нью-Йорк (Ньюарк)",
-		"LAX": "Лос-Анджелес",
-		"MIA": "Майами",
-		"ORD": "Чикаго",
-		"YYZ": "Торонто",
-		"YVR": "Ванкувер",
-
-		// Россия и СНГ
-		"SVO": "Москва (Шереметьево)",
-		"DME": "Москва (Домодедово)",
-		"VKO": "Москва (Внуково)",
-		"LED": "Санкт-Петербург",
-		"SVX": "Екатеринбург",
-		"KJA": "Красноярск",
-		"IKT": "Иркутск",
-		"VVO": "Владивосток",
-		"KHV": "Хабаровск",
-		"ALA": "Алматы",
-		"TAS": "Ташкент",
-		"FRU": "Бишкек",
-
-		// Города вылета
-		"OVB": "Новосибирск",
-		"BAX": "Барнаул",
-	}
-
-	if name, ok := cityNames[iata]; ok {
-		return name
-	}
-
-	return iata
+	return airports.DisplayName(iata)
+}
+
+// getMetroName возвращает название города без уточнения аэропорта
+func getMetroName(code string) string {
+	// Код города может совпадать с кодом аэропорта (BKK), поэтому ищем именно город
+	if city, ok := airports.City(code); ok {
+		return city.NameRu
+	}
+	return airports.DisplayName(code)
 }
 
 func getRussianDayOfWeek(day time.Weekday) string {
@@ -480,30 +730,6 @@ func getRussianDayOfWeek(day time.Weekday) string {
 	return days[day]
 }
 
-func formatDuration(minutes int) string {
-	hours := minutes / 60
-	mins := minutes % 60
-
-	if hours > 0 && mins > 0 {
-		return fmt.Sprintf("%dч %dм", hours, mins)
-	} else if hours > 0 {
-		return fmt.Sprintf("%dч", hours)
-	} else {
-		return fmt.Sprintf("%dм", mins)
-	}
-}
-
-func getTransfersText(transfers int) string {
-	switch transfers {
-	case 0:
-		return "прямой"
-	case 1:
-		return "1 перес"
-	default:
-		return fmt.Sprintf("%d перес", transfers)
-	}
-}
-
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -512,18 +738,77 @@ func min(a, b int) int {
 }
 
 func (fs *FlightSearch) SetDestination(destination string) {
-	fs.config.DestinationIATA = strings.ToUpper(destination)
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.defaults.Destination = strings.ToUpper(destination)
 }
 
 func (fs *FlightSearch) SetMonthsToSearch(monthsToSearch int) {
-	fs.config.MonthsToSearch = monthsToSearch
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.defaults.MonthsToSearch = monthsToSearch
+}
+
+// SetOriginIATA заменяет список городов вылета по умолчанию
+func (fs *FlightSearch) SetOriginIATA(origins ...string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.ToUpper(origin)
+		if !containsString(normalized, origin) {
+			normalized = append(normalized, origin)
+		}
+	}
+	fs.defaults.Origins = normalized
+}
+
+// AddOriginIATA добавляет город вылета по умолчанию.
+// Возвращает false, если он уже есть в списке.
+func (fs *FlightSearch) AddOriginIATA(origin string) bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	origin = strings.ToUpper(origin)
+	if containsString(fs.defaults.Origins, origin) {
+		return false
+	}
+	fs.defaults.Origins = append(fs.defaults.Origins, origin)
+	return true
+}
+
+// RemoveOriginIATA убирает город вылета по умолчанию. Последний город
+// убрать нельзя: поиску нужен хотя бы один город вылета.
+func (fs *FlightSearch) RemoveOriginIATA(origin string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	origin = strings.ToUpper(origin)
+	remaining := make([]string, 0, len(fs.defaults.Origins))
+	for _, existing := range fs.defaults.Origins {
+		if existing != origin {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	if len(remaining) == len(fs.defaults.Origins) {
+		return fmt.Errorf("город вылета %s не выбран", origin)
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("нельзя убрать единственный город вылета %s", origin)
+	}
+	fs.defaults.Origins = remaining
+	return nil
 }
 
-func (fs *FlightSearch) SetOriginIATA(origin string) {
-	for _, existing := range fs.config.OriginIATA {
-		if existing == origin {
-			return
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
 		}
 	}
-	fs.config.OriginIATA = []string{origin}
+	return false
 }
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxMessageLength - ограничение Telegram на длину текста сообщения
+const maxMessageLength = 4096
+
+// resultPageRows - сколько строк таблиц помещается на одну страницу результата
+const resultPageRows = 15
+
+// ResultOrder - порядок строк в таблицах результата
+type ResultOrder string
+
+const (
+	OrderByPrice    ResultOrder = "price"
+	OrderByDate     ResultOrder = "date"
+	OrderByDuration ResultOrder = "duration"
+)
+
+// resultOrders - порядки сортировки в том виде, в каком они показаны на кнопках
+var resultOrders = []struct {
+	Order ResultOrder
+	Label string
+}{
+	{OrderByPrice, "💰 Цена"},
+	{OrderByDate, "📅 Дата"},
+	{OrderByDuration, "⏱ В пути"},
+}
+
+// resultSection - таблица одного маршрута: заголовок, шапка таблицы и строки
+type resultSection struct {
+	title  string
+	header string
+	rows   []string
+}
+
+// FormatResultPages разбивает результат поиска на страницы в HTML-разметке Telegram.
+// Страницы делятся по границам строк таблиц, таблица, не поместившаяся на страницу,
+// продолжается на следующей под тем же заголовком.
+func FormatResultPages(result *SearchResult, order ResultOrder) []string {
+	if result.Empty() {
+		return []string{"ℹ️ Дешёвых билетов не найдено." + formatErrorsHTML(result.Errors)}
+	}
+
+	var title string
+	var sections []resultSection
+	var footer strings.Builder
+
+	footer.WriteString("📊 <b>Информация:</b>\n")
+	if result.Query.RoundTrip {
+		title = "🔁 <b>НАЙДЕНЫ ПОЕЗДКИ ТУДА-ОБРАТНО!</b>\n" +
+			fmt.Sprintf("<i>%d–%d ночей</i>\n\n", result.Query.MinNights, result.Query.MaxNights)
+		sections = itinerarySections(result, order)
+		footer.WriteString("   • 🎫→ / 🎫← - билеты туда и обратно\n")
+	} else {
+		title = "✈️ <b>НАЙДЕНЫ ДЕШЁВЫЕ БИЛЕТЫ!</b>\n\n"
+		for _, group := range groupByOrigin(result.Outbound) {
+			sections = append(sections, routeSection(group[0].Origin, result.Query.Destination, group, order))
+		}
+		for _, group := range groupByOrigin(result.Return) {
+			sections = append(sections, routeSection(group[0].Origin, result.Query.Origins[0], group, order))
+		}
+		footer.WriteString("   • 🎫 - ссылка на покупку\n")
+		if showsAirports(result.Query) {
+			footer.WriteString("   • SVO-DPS - аэропорты вылета и прилёта\n")
+		}
+	}
+	footer.WriteString(formatErrorsHTML(result.Errors))
+
+	// Для гибкой даты сначала показываем, какой из соседних дней дешевле
+	if result.Query.DateFilter.Mode == "flex" {
+		sections = append([]resultSection{flexGridSection(result)}, sections...)
+		footer.WriteString("<i>● - желаемая дата, * - самый дешёвый день</i>\n")
+	}
+
+	return paginateSections(title, sections, footer.String())
+}
+
+// flexGridSection строит сетку "дата вылета × город вылета" с минимальной ценой
+// в каждой клетке. Для поездок туда-обратно в клетке цена всей поездки.
+func flexGridSection(result *SearchResult) resultSection {
+	filter := result.Query.DateFilter
+
+	var origins []string
+	prices := make(map[string]map[string]int) // город вылета -> дата -> цена
+	addPrice := func(flight Flight, price int) {
+		origin := airports.CityCode(flight.Origin)
+		if prices[origin] == nil {
+			prices[origin] = make(map[string]int)
+			origins = append(origins, origin)
+		}
+		date := flight.DepartureAt.Format("2006-01-02")
+		if current, exists := prices[origin][date]; !exists || price < current {
+			prices[origin][date] = price
+		}
+	}
+	if result.Query.RoundTrip {
+		for _, trip := range result.Itineraries {
+			addPrice(trip.Outbound, trip.TotalPrice)
+		}
+	} else {
+		for _, flight := range result.Outbound {
+			addPrice(flight, flight.Price)
+		}
+	}
+
+	var header strings.Builder
+	header.WriteString("<code>Дата       ")
+	for _, origin := range origins {
+		header.WriteString(fmt.Sprintf("| %7s ", origin))
+	}
+	header.WriteString("\n</code>")
+
+	section := resultSection{
+		title:  fmt.Sprintf("🗓 <b>Цены по датам вылета, %s</b>\n", filter.String()),
+		header: header.String(),
+	}
+
+	cheapest := make(map[string]int, len(origins))
+	for _, origin := range origins {
+		for _, price := range prices[origin] {
+			if current, exists := cheapest[origin]; !exists || price < current {
+				cheapest[origin] = price
+			}
+		}
+	}
+
+	for date := filter.StartDate; !date.After(filter.EndDate); date = date.AddDate(0, 0, 1) {
+		marker := " "
+		if date.Equal(filter.Target) {
+			marker = "●"
+		}
+
+		var row strings.Builder
+		row.WriteString(fmt.Sprintf("<code>%s %s %s ", date.Format("02.01"), getRussianDayOfWeek(date.Weekday()), marker))
+		for _, origin := range origins {
+			price, exists := prices[origin][date.Format("2006-01-02")]
+			switch {
+			case !exists:
+				row.WriteString("|       — ")
+			case price == cheapest[origin]:
+				row.WriteString(fmt.Sprintf("| %6d* ", price))
+			default:
+				row.WriteString(fmt.Sprintf("| %6d  ", price))
+			}
+		}
+		row.WriteString("</code>\n")
+		section.rows = append(section.rows, row.String())
+	}
+	return section
+}
+
+// paginateSections собирает страницы из таблиц так, чтобы на странице было
+// не больше resultPageRows строк и maxMessageLength символов
+func paginateSections(title string, sections []resultSection, footer string) []string {
+	var pages []string
+	var page strings.Builder
+	rows := 0
+
+	newPage := func() {
+		pages = append(pages, page.String())
+		page.Reset()
+		page.WriteString(title)
+		rows = 0
+	}
+	fits := func(text string) bool {
+		return utf8.RuneCountInString(page.String())+utf8.RuneCountInString(text) <= maxMessageLength
+	}
+
+	page.WriteString(title)
+	for _, section := range sections {
+		head := section.title + section.header
+		for i, row := range section.rows {
+			// В начале таблицы или новой страницы нужен заголовок маршрута,
+			// таблицы на одной странице разделены пустой строкой
+			text := row
+			if i == 0 && rows > 0 {
+				text = "\n" + head + row
+			} else if i == 0 || rows == 0 {
+				text = head + row
+			}
+
+			if rows > 0 && (rows >= resultPageRows || !fits(text)) {
+				newPage()
+				text = head + row
+			}
+			page.WriteString(text)
+			rows++
+		}
+	}
+
+	footer = "\n" + footer
+	if !fits(footer) {
+		newPage()
+	}
+	page.WriteString(footer)
+	pages = append(pages, page.String())
+
+	return pages
+}
+
+// routeSection строит таблицу рейсов одного направления
+func routeSection(origin string, destination string, flights []Flight, order ResultOrder) resultSection {
+	withAirports := airports.HasSeveralAirports(origin) || airports.HasSeveralAirports(destination)
+
+	section := resultSection{
+		title: fmt.Sprintf("🛫 <b>%s → %s</b>\n", getMetroName(origin), getMetroName(destination)),
+		header: "<code>" +
+			"Дата          | Цена    | Время   | Пересад | Рейс\n" +
+			"--------------|---------|---------|---------|------\n" +
+			"</code>",
+	}
+
+	sorted := append([]Flight(nil), flights...)
+	sortFlights(sorted, order)
+
+	for _, flight := range sorted {
+		airline := flight.Airline
+		if withAirports {
+			airline = fmt.Sprintf("%s %s-%s", flight.Airline, flight.Origin, flight.Destination)
+		}
+
+		section.rows = append(section.rows, fmt.Sprintf(
+			"<code>%s %s | %6d₽ | %s | %7s | %s</code> <a href='%s'>🎫</a>\n",
+			flight.DepartureDate,
+			flight.DayOfWeek,
+			flight.Price,
+			formatDuration(flight.Duration),
+			getTransfersText(flight.Transfers),
+			airline,
+			flight.Link,
+		))
+	}
+	return section
+}
+
+// itinerarySections строит таблицы поездок туда-обратно по городам вылета
+func itinerarySections(result *SearchResult, order ResultOrder) []resultSection {
+	var origins []string
+	byOrigin := make(map[string][]Itinerary)
+	for _, trip := range result.Itineraries {
+		origin := airports.CityCode(trip.Outbound.Origin)
+		if _, exists := byOrigin[origin]; !exists {
+			origins = append(origins, origin)
+		}
+		byOrigin[origin] = append(byOrigin[origin], trip)
+	}
+
+	sections := make([]resultSection, 0, len(origins))
+	for _, origin := range origins {
+		trips := byOrigin[origin]
+		sortItineraries(trips, order)
+
+		section := resultSection{
+			title: fmt.Sprintf("🛫 <b>%s ⇄ %s</b>\n", getMetroName(origin), getMetroName(result.Query.Destination)),
+			header: "<code>" +
+				"Туда          | Обратно       | Ноч | Цена\n" +
+				"--------------|---------------|-----|--------\n" +
+				"</code>",
+		}
+		for _, trip := range trips {
+			row := fmt.Sprintf(
+				"<code>%s %s | %s %s | %3d | %6d₽</code> ",
+				trip.Outbound.DepartureDate,
+				trip.Outbound.DayOfWeek,
+				trip.Return.DepartureDate,
+				trip.Return.DayOfWeek,
+				trip.Nights,
+				trip.TotalPrice,
+			)
+			if showsAirports(result.Query) {
+				row += fmt.Sprintf("<code>%s-%s/%s-%s</code> ",
+					trip.Outbound.Origin, trip.Outbound.Destination, trip.Return.Origin, trip.Return.Destination)
+			}
+			row += fmt.Sprintf("<a href='%s'>🎫→</a> <a href='%s'>🎫←</a>\n", trip.Outbound.Link, trip.Return.Link)
+			section.rows = append(section.rows, row)
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// sortFlights упорядочивает рейсы; при равенстве выше более дешёвый
+func sortFlights(flights []Flight, order ResultOrder) {
+	sort.SliceStable(flights, func(i, j int) bool {
+		a, b := flights[i], flights[j]
+		switch order {
+		case OrderByDate:
+			if !a.DepartureAt.Equal(b.DepartureAt) {
+				return a.DepartureAt.Before(b.DepartureAt)
+			}
+		case OrderByDuration:
+			if a.Duration != b.Duration {
+				return a.Duration < b.Duration
+			}
+		}
+		return a.Price < b.Price
+	})
+}
+
+// sortItineraries упорядочивает поездки: по дате вылета туда или по времени в пути в обе стороны
+func sortItineraries(trips []Itinerary, order ResultOrder) {
+	sort.SliceStable(trips, func(i, j int) bool {
+		a, b := trips[i], trips[j]
+		switch order {
+		case OrderByDate:
+			if !a.Outbound.DepartureAt.Equal(b.Outbound.DepartureAt) {
+				return a.Outbound.DepartureAt.Before(b.Outbound.DepartureAt)
+			}
+		case OrderByDuration:
+			if da, db := a.Outbound.Duration+a.Return.Duration, b.Outbound.Duration+b.Return.Duration; da != db {
+				return da < db
+			}
+		}
+		return a.TotalPrice < b.TotalPrice
+	})
+}
+
+// showsAirports сообщает, нужно ли показывать аэропорты в таблице:
+// только если на одном из концов маршрута город с несколькими аэропортами
+func showsAirports(query SearchQuery) bool {
+	if airports.HasSeveralAirports(query.Destination) {
+		return true
+	}
+	for _, origin := range query.Origins {
+		if airports.HasSeveralAirports(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByOrigin группирует рейсы по городу вылета (все аэропорты города вместе),
+// сохраняя порядок появления
+func groupByOrigin(flights []Flight) [][]Flight {
+	var order []string
+	byOrigin := make(map[string][]Flight)
+	for _, flight := range flights {
+		city := airports.CityCode(flight.Origin)
+		if _, exists := byOrigin[city]; !exists {
+			order = append(order, city)
+		}
+		byOrigin[city] = append(byOrigin[city], flight)
+	}
+
+	groups := make([][]Flight, 0, len(order))
+	for _, city := range order {
+		groups = append(groups, byOrigin[city])
+	}
+	return groups
+}
+
+// SplitMessageHTML делит длинный текст на части не длиннее maxMessageLength
+// по границам строк. Разметка в этом боте не переносится через строку,
+// поэтому каждая часть остается корректным HTML.
+func SplitMessageHTML(text string) []string {
+	return splitLines(text, maxMessageLength)
+}
+
+// splitLines делит текст на части не длиннее limit символов по границам строк
+func splitLines(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	var part strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if part.Len() > 0 && utf8.RuneCountInString(part.String())+utf8.RuneCountInString(line) > limit {
+			parts = append(parts, part.String())
+			part.Reset()
+		}
+		part.WriteString(line)
+	}
+	if part.Len() > 0 {
+		parts = append(parts, part.String())
+	}
+	return parts
+}
+
+// FormatAlertsHTML отображает уведомления о снижении цен: старая и новая цена рядом
+func FormatAlertsHTML(title string, alerts []PriceAlert) string {
+	var sb strings.Builder
+
+	sb.WriteString(title + "\n\n")
+
+	for _, alert := range alerts {
+		flight := alert.Flight
+		sb.WriteString(fmt.Sprintf("🛫 <b>%s → %s</b>, %s %s %s\n",
+			getCityName(flight.Origin),
+			getCityName(flight.Destination),
+			flight.DepartureDate,
+			flight.DayOfWeek,
+			flight.DepartureTime,
+		))
+		sb.WriteString(fmt.Sprintf("   <s>%d₽</s> → <b>%d₽</b> (−%d%%) | %s | %s | %s ",
+			alert.OldPrice,
+			flight.Price,
+			alert.DropPercent(),
+			formatDuration(flight.Duration),
+			getTransfersText(flight.Transfers),
+			flight.Airline,
+		))
+		sb.WriteString(fmt.Sprintf("<a href='%s'>🎫</a>\n", flight.Link))
+		if alert.Kind == AlertAllTimeLow {
+			sb.WriteString(fmt.Sprintf("   🏆 <i>Новый исторический минимум (был %d₽)</i>\n", alert.LowPrice))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// FormatWeekendsHTML отображает поездки на выходные: по каждому направлению
+// самая дешёвая поездка на каждые выходные и лучшие выходные
+func FormatWeekendsHTML(query SearchQuery, reports []WeekendReport, rules WeekendRules) string {
+	var sb strings.Builder
+
+	sb.WriteString("🏖 <b>ПОЕЗДКИ НА ВЫХОДНЫЕ</b>\n")
+	sb.WriteString(fmt.Sprintf("<i>%s</i>\n\n", html.EscapeString(rules.String())))
+
+	var errors []LegError
+	for _, report := range reports {
+		sb.WriteString(fmt.Sprintf("🛫 <b>%s ⇄ %s</b>\n", strings.Join(query.Origins, "/"), getMetroName(report.Destination)))
+
+		if report.Err != nil {
+			sb.WriteString(fmt.Sprintf("❌ <i>%s</i>\n\n", html.EscapeString(report.Err.Error())))
+			continue
+		}
+		errors = append(errors, report.Errors...)
+
+		best, found := report.Cheapest()
+		if !found {
+			sb.WriteString("ℹ️ Подходящих поездок не найдено\n\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("🏆 Лучшие выходные: <b>%s</b> за <b>%d₽</b>\n", weekendLabel(best.Friday), best.TotalPrice))
+
+		trips := make(map[time.Time]WeekendTrip, len(report.Trips))
+		for _, trip := range report.Trips {
+			trips[trip.Friday] = trip
+		}
+		for _, friday := range report.Fridays {
+			trip, exists := trips[friday]
+			if !exists {
+				sb.WriteString(fmt.Sprintf("<code>%s | нет вариантов</code>\n", weekendLabel(friday)))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("<code>%s | %s %s %s → %s %s | %6d₽</code> <a href='%s'>🎫→</a> <a href='%s'>🎫←</a>\n",
+				weekendLabel(friday),
+				trip.Outbound.Origin,
+				trip.Outbound.DayOfWeek,
+				trip.Outbound.DepartureTime,
+				trip.Return.DayOfWeek,
+				trip.Return.DepartureTime,
+				trip.TotalPrice,
+				trip.Outbound.Link,
+				trip.Return.Link,
+			))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(formatErrorsHTML(errors))
+	return sb.String()
+}
+
+// weekendLabel - выходные по пятнице: "13.11–15.11"
+func weekendLabel(friday time.Time) string {
+	return friday.Format("02.01") + "–" + friday.AddDate(0, 0, 2).Format("02.01")
+}
+
+// maxExploreDestinations - сколько направлений показывать в результате /explore
+const maxExploreDestinations = 50
+
+// FormatExploreHTML форматирует самые дешёвые направления, сгруппированные по странам
+func FormatExploreHTML(result *ExploreResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("🌍 <b>КУДА ПОЛЕТЕТЬ</b>\n")
+	sb.WriteString(fmt.Sprintf("Из: <b>%s</b>, месяцы: <b>%s</b>", strings.Join(result.Origins, ", "), formatMonthRange(result.Months)))
+	if result.Budget > 0 {
+		sb.WriteString(fmt.Sprintf(", до <b>%d₽</b>", result.Budget))
+	}
+	sb.WriteString("\n<i>Цены из кэша поиска, перед покупкой проверьте по ссылке</i>\n\n")
+
+	if len(result.Destinations) == 0 {
+		sb.WriteString("ℹ️ Направлений в пределах бюджета не найдено\n")
+		sb.WriteString(formatErrorsHTML(result.Errors))
+		return sb.String()
+	}
+
+	// В список попадают самые дешёвые направления, страны группируются уже среди них
+	top := *result
+	top.Destinations = result.Destinations[:min(maxExploreDestinations, len(result.Destinations))]
+	for _, country := range top.ByCountry() {
+		sb.WriteString(fmt.Sprintf("📍 <b>%s</b>\n", html.EscapeString(country.Name)))
+		for _, destination := range country.Destinations {
+			flight := destination.Flight
+			sb.WriteString(fmt.Sprintf("<code>%s %s | %7s | %6d₽</code> %s <a href='%s'>🎫</a>\n",
+				flight.Origin,
+				flight.DepartureDate,
+				getTransfersText(flight.Transfers),
+				flight.Price,
+				html.EscapeString(destination.Name),
+				flight.Link,
+			))
+		}
+		sb.WriteString("\n")
+	}
+	if hidden := len(result.Destinations) - len(top.Destinations); hidden > 0 {
+		sb.WriteString(fmt.Sprintf("… и ещё %d направлений дороже\n", hidden))
+	}
+
+	sb.WriteString(formatErrorsHTML(result.Errors))
+	return sb.String()
+}
+
+// formatErrorsHTML перечисляет плечи поиска, которые не удалось проверить
+func formatErrorsHTML(errors []LegError) string {
+	if len(errors) == 0 {
+		return ""
+	}
+
+	const maxListed = 10
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n⚠️ <b>Результат неполный, не удалось проверить %d запрос(ов):</b>\n", len(errors)))
+	for _, legErr := range errors[:min(maxListed, len(errors))] {
+		mark := "🔁"
+		if legErr.Permanent() {
+			mark = "⛔"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s → %s, %s: <i>%s</i>\n",
+			mark,
+			legErr.Origin,
+			legErr.Destination,
+			legErr.Month,
+			html.EscapeString(legErr.Description()),
+		))
+	}
+	if len(errors) > maxListed {
+		sb.WriteString(fmt.Sprintf("… и ещё %d\n", len(errors)-maxListed))
+	}
+	sb.WriteString("<i>🔁 - временный сбой, ⛔ - ошибка не исчезнет при повторе</i>\n")
+	return sb.String()
+}
+
+func formatDuration(minutes int) string {
+	hours := minutes / 60
+	mins := minutes % 60
+
+	if hours > 0 && mins > 0 {
+		return fmt.Sprintf("%dч %dм", hours, mins)
+	} else if hours > 0 {
+		return fmt.Sprintf("%dч", hours)
+	} else {
+		return fmt.Sprintf("%dм", mins)
+	}
+}
+
+func getTransfersText(transfers int) string {
+	switch transfers {
+	case 0:
+		return "прямой"
+	case 1:
+		return "1 перес"
+	default:
+		return fmt.Sprintf("%d перес", transfers)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PriceObservation - одно наблюдение цены билета.
+// Короткие JSON-ключи уменьшают размер файла истории.
+type PriceObservation struct {
+	Origin      string `json:"o"`
+	Destination string `json:"d"`
+	DepartureAt string `json:"t"` // Время вылета в формате "2006-01-02T15:04"
+	Airline     string `json:"a"`
+	Transfers   int    `json:"tr"`
+	Duration    int    `json:"du"`
+	Price       int    `json:"p"`
+	ObservedAt  int64  `json:"ts"` // Unix-время наблюдения
+}
+
+// DepartureDate возвращает дату вылета в формате "2006-01-02"
+func (o PriceObservation) DepartureDate() string {
+	if len(o.DepartureAt) < 10 {
+		return o.DepartureAt
+	}
+	return o.DepartureAt[:10]
+}
+
+func (o PriceObservation) ObservedTime() time.Time {
+	return time.Unix(o.ObservedAt, 0)
+}
+
+// PriceHistory - хранилище всех наблюдённых цен в файле JSON Lines.
+// Новые наблюдения дописываются в конец файла, устаревшие удаляются
+// при открытии и не чаще раза в сутки при записи.
+type PriceHistory struct {
+	mu          sync.RWMutex
+	path        string
+	retention   time.Duration
+	byRoute     map[string][]PriceObservation
+	lastCompact time.Time
+}
+
+func NewPriceHistory(path string, retentionDays int) (*PriceHistory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("создание каталога истории: %w", err)
+	}
+
+	h := &PriceHistory{
+		path:      path,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		byRoute:   make(map[string][]PriceObservation),
+	}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	if err := h.compact(time.Now()); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func routeDateKey(origin, destination, date string) string {
+	return origin + "|" + destination + "|" + date
+}
+
+func (h *PriceHistory) load() error {
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("открытие истории цен: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var obs PriceObservation
+		if err := json.Unmarshal(scanner.Bytes(), &obs); err != nil {
+			// Повреждённая строка (например, после аварийной остановки) не должна ломать загрузку
+			continue
+		}
+		key := routeDateKey(obs.Origin, obs.Destination, obs.DepartureDate())
+		h.byRoute[key] = append(h.byRoute[key], obs)
+	}
+	return scanner.Err()
+}
+
+// Record сохраняет наблюдения цен для всех переданных рейсов
+func (h *PriceHistory) Record(flights []Flight, observedAt time.Time) error {
+	if len(flights) == 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("запись истории цен: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, flight := range flights {
+		obs := PriceObservation{
+			Origin:      flight.Origin,
+			Destination: flight.Destination,
+			DepartureAt: flight.DepartureAt.Format("2006-01-02T15:04"),
+			Airline:     flight.Airline,
+			Transfers:   flight.Transfers,
+			Duration:    flight.Duration,
+			Price:       flight.Price,
+			ObservedAt:  observedAt.Unix(),
+		}
+		if err := encoder.Encode(obs); err != nil {
+			return fmt.Errorf("запись истории цен: %w", err)
+		}
+		key := routeDateKey(obs.Origin, obs.Destination, obs.DepartureDate())
+		h.byRoute[key] = append(h.byRoute[key], obs)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("запись истории цен: %w", err)
+	}
+
+	if observedAt.Sub(h.lastCompact) > 24*time.Hour {
+		if err := h.compact(observedAt); err != nil {
+			log.Printf("⚠️ Не удалось сжать историю цен: %v", err)
+		}
+	}
+	return nil
+}
+
+// Observations возвращает наблюдения для маршрута и даты вылета ("2006-01-02"),
+// упорядоченные по времени наблюдения
+func (h *PriceHistory) Observations(origin, destination, date string) []PriceObservation {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stored := h.byRoute[routeDateKey(origin, destination, date)]
+	result := make([]PriceObservation, len(stored))
+	copy(result, stored)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ObservedAt < result[j].ObservedAt
+	})
+	return result
+}
+
+// compact удаляет наблюдения старше срока хранения и перезаписывает файл.
+// Вызывается под блокировкой или до начала параллельного использования.
+func (h *PriceHistory) compact(now time.Time) error {
+	h.lastCompact = now
+	if h.retention <= 0 {
+		return nil
+	}
+
+	cutoff := now.Add(-h.retention).Unix()
+	removed := 0
+	for key, observations := range h.byRoute {
+		kept := observations[:0]
+		for _, obs := range observations {
+			if obs.ObservedAt >= cutoff {
+				kept = append(kept, obs)
+			}
+		}
+		removed += len(observations) - len(kept)
+		if len(kept) == 0 {
+			delete(h.byRoute, key)
+		} else {
+			h.byRoute[key] = kept
+		}
+	}
+
+	if removed == 0 {
+		return nil
+	}
+
+	tmpPath := h.path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, observations := range h.byRoute {
+		for _, obs := range observations {
+			if err := encoder.Encode(obs); err != nil {
+				file.Close()
+				return err
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("🧹 Из истории цен удалено устаревших наблюдений: %d", removed)
+	return os.Rename(tmpPath, h.path)
+}
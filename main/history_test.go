@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// historyLines считает наблюдения в файле истории
+func historyLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestPriceHistoryRetentionOnOpen(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		retentionDays int
+		want          int
+	}{
+		{"срок хранения 30 дней", 30, 2},
+		{"срок хранения 3 дня", 3, 1},
+		{"без срока хранения", 0, 3},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		history, err := NewPriceHistory(path, tt.retentionDays)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, age := range []time.Duration{40 * 24 * time.Hour, 10 * 24 * time.Hour, time.Hour} {
+			// Наблюдения в прошлом не запускают сжатие при записи
+			if err := history.Record([]Flight{tripFlight("OVB", "AER", "2026-11-20 10:00", 10000)}, now.Add(-age)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		reopened, err := NewPriceHistory(path, tt.retentionDays)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(reopened.Observations("OVB", "AER", "2026-11-20")); got != tt.want {
+			t.Errorf("%s: после открытия наблюдений %d, ожидалось %d", tt.name, got, tt.want)
+		}
+		if got := historyLines(t, path); got != tt.want {
+			t.Errorf("%s: в файле наблюдений %d, ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPriceHistoryCompactsOnRecordOncePerDay(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	history, err := NewPriceHistory(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(date string, observedAt time.Time) {
+		t.Helper()
+		if err := history.Record([]Flight{tripFlight("OVB", "AER", date+" 10:00", 10000)}, observedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	record("2026-11-20", now.Add(-2*24*time.Hour))
+	if got := len(history.Observations("OVB", "AER", "2026-11-20")); got != 1 {
+		t.Fatalf("сжатие запущено раньше чем через сутки: наблюдений %d", got)
+	}
+
+	// Спустя сутки с последнего сжатия устаревшее наблюдение удаляется и из файла
+	record("2026-11-21", now.Add(25*time.Hour))
+	if got := len(history.Observations("OVB", "AER", "2026-11-20")); got != 0 {
+		t.Errorf("устаревших наблюдений осталось %d", got)
+	}
+	if got := historyLines(t, path); got != 1 {
+		t.Errorf("в файле наблюдений %d, ожидалось 1", got)
+	}
+
+	// Следующее сжатие - не раньше чем через сутки
+	record("2026-11-22", now.Add(-2*24*time.Hour))
+	record("2026-11-23", now.Add(30*time.Hour))
+	if got := len(history.Observations("OVB", "AER", "2026-11-22")); got != 1 {
+		t.Errorf("сжатие повторилось раньше чем через сутки: наблюдений %d", got)
+	}
+	if got := historyLines(t, path); got != 3 {
+		t.Errorf("в файле наблюдений %d, ожидалось 3", got)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LedgerEntry - последнее отправленное чату уведомление о рейсе.
+// Короткие JSON-ключи, как в истории цен.
+type LedgerEntry struct {
+	Price  int       `json:"p"`
+	Kind   AlertKind `json:"k"`
+	SentAt int64     `json:"ts"` // Unix-время отправки
+}
+
+// LedgerPolicy задает, когда уведомление о уже отправленном рейсе повторяется
+type LedgerPolicy struct {
+	Window           time.Duration // В течение этого времени повтор подавляется
+	MinChangeRub     int           // Повторить, если цена снизилась ещё хотя бы на столько рублей
+	MinChangePercent int           // или на столько процентов
+}
+
+// NotificationLedger помнит, о каких рейсах (маршрут, время вылета, авиакомпания)
+// и по какой цене уже сообщено каждому чату, чтобы не присылать одно и то же
+// при каждом запуске по расписанию
+type NotificationLedger struct {
+	mu     sync.Mutex
+	path   string
+	policy LedgerPolicy
+	Sent   map[int64]map[string]LedgerEntry `json:"sent"`
+}
+
+func NewNotificationLedger(path string, policy LedgerPolicy) (*NotificationLedger, error) {
+	ledger := &NotificationLedger{
+		path:   path,
+		policy: policy,
+		Sent:   make(map[int64]map[string]LedgerEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("чтение журнала уведомлений: %w", err)
+	}
+	if err := json.Unmarshal(data, ledger); err != nil {
+		return nil, fmt.Errorf("разбор журнала уведомлений: %w", err)
+	}
+	if ledger.Sent == nil {
+		ledger.Sent = make(map[int64]map[string]LedgerEntry)
+	}
+	return ledger, nil
+}
+
+// fareKey - рейс без цены: маршрут, время вылета и авиакомпания
+func fareKey(flight Flight) string {
+	return fmt.Sprintf("%s|%s|%s|%s", flight.Origin, flight.Destination,
+		flight.DepartureAt.Format("2006-01-02T15:04"), flight.Airline)
+}
+
+// Fresh оставляет уведомления, о которых чату ещё не сообщалось. Повтор
+// пропускается, если с прошлой отправки прошло меньше Window и цена
+// существенно не изменилась; новый исторический минимум сообщается всегда.
+// Журнал не меняется: отправленные уведомления записывает Record.
+func (l *NotificationLedger) Fresh(chatID int64, alerts []PriceAlert, now time.Time) []PriceAlert {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sent := l.Sent[chatID]
+	seen := make(map[string]bool)
+
+	var fresh []PriceAlert
+	for _, alert := range alerts {
+		key := fareKey(alert.Flight)
+		if entry, exists := sent[key]; exists && !l.changed(entry, alert, now) {
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fresh = append(fresh, alert)
+	}
+	return fresh
+}
+
+// Record запоминает уведомления как отправленные чату. Вызывается после
+// успешной отправки или постановки в очередь, чтобы неотправленное
+// уведомление не считалось доставленным.
+func (l *NotificationLedger) Record(chatID int64, alerts []PriceAlert, now time.Time) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	sent := l.Sent[chatID]
+	if sent == nil {
+		sent = make(map[string]LedgerEntry)
+		l.Sent[chatID] = sent
+	}
+	for _, alert := range alerts {
+		sent[fareKey(alert.Flight)] = LedgerEntry{Price: alert.Flight.Price, Kind: alert.Kind, SentAt: now.Unix()}
+	}
+
+	l.prune(now)
+	return l.save()
+}
+
+// changed проверяет, стоит ли повторить уведомление о рейсе
+func (l *NotificationLedger) changed(entry LedgerEntry, alert PriceAlert, now time.Time) bool {
+	if now.Sub(time.Unix(entry.SentAt, 0)) >= l.policy.Window {
+		return true
+	}
+	if alert.Kind == AlertAllTimeLow && alert.Flight.Price < entry.Price {
+		return true
+	}
+
+	drop := entry.Price - alert.Flight.Price
+	if drop <= 0 {
+		return false
+	}
+	if l.policy.MinChangeRub == 0 && l.policy.MinChangePercent == 0 {
+		return true
+	}
+	if l.policy.MinChangeRub > 0 && drop >= l.policy.MinChangeRub {
+		return true
+	}
+	return l.policy.MinChangePercent > 0 && drop*100/entry.Price >= l.policy.MinChangePercent
+}
+
+// prune удаляет записи старше Window: повтор по ним уже разрешен.
+// Вызывается под блокировкой.
+func (l *NotificationLedger) prune(now time.Time) {
+	for chatID, sent := range l.Sent {
+		for key, entry := range sent {
+			if now.Sub(time.Unix(entry.SentAt, 0)) >= l.policy.Window {
+				delete(sent, key)
+			}
+		}
+		if len(sent) == 0 {
+			delete(l.Sent, chatID)
+		}
+	}
+}
+
+// save атомарно перезаписывает файл журнала. Вызывается под блокировкой.
+func (l *NotificationLedger) save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("создание каталога журнала уведомлений: %w", err)
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := l.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("запись журнала уведомлений: %w", err)
+	}
+	return os.Rename(tmpPath, l.path)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotifier - канал уведомлений для тестов: запоминает уведомления
+// или отвечает ошибкой err
+type fakeNotifier struct {
+	name string
+
+	mu   sync.Mutex
+	err  error
+	sent []Notification
+}
+
+func (n *fakeNotifier) Name() string {
+	return n.name
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func (n *fakeNotifier) fail(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.err = err
+}
+
+func (n *fakeNotifier) Sent() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]Notification(nil), n.sent...)
+}
+
+func newTestDelivery(t *testing.T, notifiers ...Notifier) (*AlertDelivery, *NotificationLedger) {
+	t.Helper()
+
+	dir := t.TempDir()
+	config := &AppConfig{Schedule: defaultSchedule}
+	byName := make(map[string]Notifier)
+	for _, notifier := range notifiers {
+		config.DefaultSinks = append(config.DefaultSinks, notifier.Name())
+		byName[notifier.Name()] = notifier
+	}
+	subscriptions, err := NewSubscriptionStore(filepath.Join(dir, "subscriptions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox, err := NewOutbox(filepath.Join(dir, "outbox.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := NewNotificationLedger(filepath.Join(dir, "notified.json"), LedgerPolicy{Window: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewAlertDelivery(config, byName, subscriptions, NewScheduler(config, subscriptions), outbox, ledger), ledger
+}
+
+func testAlert(price int) PriceAlert {
+	flight := testFlight("2026-11-13 09:30", 300, 0, "S7")
+	flight.Price = price
+	return PriceAlert{Flight: flight, Kind: AlertPriceDrop, OldPrice: price + 2000}
+}
+
+func TestLedgerFreshDoesNotRecord(t *testing.T) {
+	ledger, err := NewNotificationLedger(filepath.Join(t.TempDir(), "notified.json"), LedgerPolicy{Window: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	alerts := []PriceAlert{testAlert(10000), testAlert(10000)}
+
+	if fresh := ledger.Fresh(1, alerts, now); len(fresh) != 1 {
+		t.Fatalf("новых уведомлений %d, ожидалось одно: дубль в пачке пропускается", len(fresh))
+	}
+	if fresh := ledger.Fresh(1, alerts, now); len(fresh) != 1 {
+		t.Fatal("Fresh не должен записывать уведомления в журнал")
+	}
+
+	if err := ledger.Record(1, alerts[:1], now); err != nil {
+		t.Fatal(err)
+	}
+	if fresh := ledger.Fresh(1, alerts, now.Add(time.Minute)); len(fresh) != 0 {
+		t.Errorf("записанное уведомление повторено: %+v", fresh)
+	}
+	if fresh := ledger.Fresh(2, alerts, now.Add(time.Minute)); len(fresh) != 1 {
+		t.Error("журнал одного чата не должен влиять на другой")
+	}
+	if fresh := ledger.Fresh(1, alerts, now.Add(time.Hour)); len(fresh) != 1 {
+		t.Error("после окна повтора уведомление должно повториться")
+	}
+}
+
+func TestDeliverRecordsOnlyDeliveredAlerts(t *testing.T) {
+	notifier := &fakeNotifier{name: "fake"}
+	delivery, ledger := newTestDelivery(t, notifier)
+	sub := Subscription{ID: 1, ChatID: 1}
+	alerts := []PriceAlert{testAlert(10000)}
+	now := time.Now()
+
+	// Канал недоступен: уведомление не отправлено и не должно считаться отправленным
+	notifier.fail(errors.New("канал недоступен"))
+	delivery.Deliver(sub, alerts, now)
+	if fresh := ledger.Fresh(sub.ChatID, alerts, now); len(fresh) != 1 {
+		t.Fatal("неотправленное уведомление записано в журнал")
+	}
+
+	notifier.fail(nil)
+	delivery.Deliver(sub, alerts, now.Add(time.Minute))
+	if len(notifier.Sent()) != 1 {
+		t.Fatalf("после восстановления канала отправлено %d уведомлений, ожидалось одно", len(notifier.Sent()))
+	}
+
+	delivery.Deliver(sub, alerts, now.Add(2*time.Minute))
+	if len(notifier.Sent()) != 1 {
+		t.Error("уже отправленное уведомление отправлено повторно")
+	}
+}
+
+func TestFlushKeepsQueuedAlertsUntilDelivered(t *testing.T) {
+	notifier := &fakeNotifier{name: "fake"}
+	delivery, ledger := newTestDelivery(t, notifier)
+	sub := Subscription{ID: 1, ChatID: 1}
+	alerts := []PriceAlert{testAlert(10000)}
+	now := time.Date(2026, 11, 13, 7, 0, 0, 0, time.Local)
+
+	err := delivery.subscriptions.UpdateSettings(sub.ChatID, func(settings *ChatSettings) {
+		settings.Digest = "0 9 * * *"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// До дайджеста уведомление ждет в очереди и в журнал не попадает;
+	// повторное снижение того же рейса не дублируется в очереди
+	delivery.Deliver(sub, alerts, now)
+	delivery.Deliver(sub, alerts, now.Add(time.Hour))
+	if len(notifier.Sent()) != 0 {
+		t.Fatal("уведомление для дайджеста отправлено сразу")
+	}
+	if queued := delivery.outbox.Queued(sub.ChatID); len(queued) != 1 {
+		t.Fatalf("в очереди %d уведомлений, ожидалось одно", len(queued))
+	}
+	if fresh := ledger.Fresh(sub.ChatID, alerts, now); len(fresh) != 1 {
+		t.Error("отложенное уведомление записано в журнал до отправки")
+	}
+
+	// Канал недоступен во время дайджеста: уведомление остается в очереди
+	digest := now.Add(2 * time.Hour)
+	notifier.fail(errors.New("канал недоступен"))
+	delivery.Flush(digest)
+	if queued := delivery.outbox.Queued(sub.ChatID); len(queued) != 1 {
+		t.Fatalf("после сбоя канала в очереди %d уведомлений, ожидалось одно", len(queued))
+	}
+	if fresh := ledger.Fresh(sub.ChatID, alerts, digest); len(fresh) != 1 {
+		t.Error("неотправленный дайджест записан в журнал")
+	}
+
+	notifier.fail(nil)
+	delivery.Flush(digest.Add(time.Minute))
+	sent := notifier.Sent()
+	if len(sent) != 1 || sent[0].Kind != NotifyDigest || len(sent[0].Alerts) != 1 {
+		t.Fatalf("после восстановления канала отправлено: %+v", sent)
+	}
+	if queued := delivery.outbox.Queued(sub.ChatID); len(queued) != 0 {
+		t.Errorf("доставленное уведомление осталось в очереди: %+v", queued)
+	}
+	if fresh := ledger.Fresh(sub.ChatID, alerts, digest.Add(time.Minute)); len(fresh) != 0 {
+		t.Error("доставленный дайджест не записан в журнал")
+	}
+
+	delivery.Flush(digest.Add(2 * time.Minute))
+	if len(notifier.Sent()) != 1 {
+		t.Error("дайджест отправлен повторно")
+	}
+}
+
+func TestFlushRetriesOnlyFailedSinks(t *testing.T) {
+	working := &fakeNotifier{name: "working"}
+	failing := &fakeNotifier{name: "failing", err: errors.New("канал недоступен")}
+	delivery, ledger := newTestDelivery(t, working, failing)
+	sub := Subscription{ID: 1, ChatID: 1}
+	alerts := []PriceAlert{testAlert(10000)}
+	now := time.Now()
+
+	if err := delivery.outbox.Queue(sub.ChatID, sub.ID, alerts, now); err != nil {
+		t.Fatal(err)
+	}
+	delivery.Flush(now)
+	if len(working.Sent()) != 1 {
+		t.Fatalf("в рабочий канал отправлено %d уведомлений", len(working.Sent()))
+	}
+	if fresh := ledger.Fresh(sub.ChatID, alerts, now); len(fresh) != 0 {
+		t.Error("уведомление, дошедшее в один из каналов, не записано в журнал")
+	}
+	queued := delivery.outbox.Queued(sub.ChatID)
+	if len(queued) != 1 || fmt.Sprint(queued[0].Sent) != "[working]" {
+		t.Fatalf("в очереди должно остаться уведомление для failing: %+v", queued)
+	}
+
+	// Повтор уходит только в канал, куда уведомление не дошло
+	failing.fail(nil)
+	delivery.Flush(now.Add(time.Minute))
+	if len(working.Sent()) != 1 || len(failing.Sent()) != 1 {
+		t.Errorf("повтор: working %d, failing %d, ожидалось по одному", len(working.Sent()), len(failing.Sent()))
+	}
+	if queued := delivery.outbox.Queued(sub.ChatID); len(queued) != 0 {
+		t.Errorf("очередь не опустела: %+v", queued)
+	}
+}
+
+func TestFlushDropsAlertsAfterRetention(t *testing.T) {
+	failing := &fakeNotifier{name: "failing", err: errors.New("канал недоступен")}
+	delivery, _ := newTestDelivery(t, failing)
+	now := time.Now()
+
+	if err := delivery.outbox.Queue(1, 1, []PriceAlert{testAlert(10000)}, now); err != nil {
+		t.Fatal(err)
+	}
+	delivery.Flush(now.Add(time.Hour))
+	if len(delivery.outbox.Queued(1)) != 1 {
+		t.Fatal("уведомление удалено до истечения срока повторов")
+	}
+	delivery.Flush(now.Add(outboxRetention))
+	if len(delivery.outbox.Queued(1)) != 0 {
+		t.Error("уведомление повторяется дольше outboxRetention")
+	}
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-
-	"github.com/robfig/cron/v3"
+	"time"
+	_ "time/tzdata" // Часовые пояса расписаний нужны и в образе без системной базы
 )
 
 func main() {
@@ -16,43 +17,127 @@ func main() {
 
 	fmt.Println("🚀 Запускаем трекер авиабилетов с Telegram ботом...")
 
+	// Справочник аэропортов можно заменить своим файлом
+	if config.AirportsFile != "" {
+		directory, err := LoadAirportDirectory(config.AirportsFile)
+		if err != nil {
+			log.Fatalf("Ошибка загрузки справочника аэропортов: %v", err)
+		}
+		airports = directory
+		log.Printf("🗺 Загружен справочник аэропортов из %s", config.AirportsFile)
+	}
+
+	// Создаем поставщиков цен
+	providers, err := newFareProviders(config)
+	if err != nil {
+		log.Fatalf("Ошибка настройки поставщиков цен: %v", err)
+	}
+
+	// Открываем историю цен
+	history, err := NewPriceHistory(config.HistoryPath, config.HistoryRetentionDays)
+	if err != nil {
+		log.Fatalf("Ошибка открытия истории цен: %v", err)
+	}
+
 	// Создаем поисковый сервис
-	flightSearch := NewFlightSearch(config)
+	flightSearch := NewFlightSearch(config, providers, history)
+
+	// Загружаем подписки пользователей
+	subscriptions, err := NewSubscriptionStore(config.SubscriptionsPath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки подписок: %v", err)
+	}
+	seedSubscriptions(config, flightSearch, subscriptions)
+
+	// Планировщик проверок подписок по их расписаниям
+	scheduler := NewScheduler(config, subscriptions)
 
 	// Создаем бота
-	bot, err := NewBot(config, flightSearch)
+	bot, err := NewBot(config, flightSearch, subscriptions, scheduler)
 	if err != nil {
 		log.Fatalf("Ошибка создания бота: %v", err)
 	}
 
+	// Уведомления, отложенные на тихие часы и до дайджеста
+	outbox, err := NewOutbox(config.OutboxPath)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки отложенных уведомлений: %v", err)
+	}
+
+	// Журнал отправленных уведомлений, чтобы не повторять одни и те же цены
+	ledger, err := NewNotificationLedger(config.LedgerPath, config.Ledger)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки журнала уведомлений: %v", err)
+	}
+
+	// Каналы уведомлений: Telegram, почта, Slack, Discord, вебхук
+	notifiers, err := newNotifiers(config, bot.api)
+	if err != nil {
+		log.Fatalf("Ошибка настройки каналов уведомлений: %v", err)
+	}
+	delivery := NewAlertDelivery(config, notifiers, subscriptions, scheduler, outbox, ledger)
+	go delivery.Run()
+
 	// Запускаем автоматический поиск по расписанию
-	go startScheduledSearch(bot, config, flightSearch)
+	go startScheduledSearch(delivery, scheduler, config, flightSearch, history)
 
 	// Запускаем бота (блокирующая операция)
 	bot.Start()
 }
 
-func startScheduledSearch(bot *Bot, config *AppConfig, flightSearch *FlightSearch) {
-	c := cron.New()
-
-	// Автоматический поиск каждый день в 10:00
-	c.AddFunc("0 10 * * *", func() {
-		log.Println("🕙 Запуск автоматического поиска по расписанию...")
+// seedSubscriptions переносит маршрут из конфигурации в подписки администраторов
+// при первом запуске, чтобы ежедневный отчёт продолжил приходить
+func seedSubscriptions(config *AppConfig, flightSearch *FlightSearch, subscriptions *SubscriptionStore) {
+	if len(subscriptions.All()) > 0 || config.DestinationIATA == "" {
+		return
+	}
 
-		result, err := flightSearch.Search()
+	query := flightSearch.DefaultQuery()
+	for _, adminID := range config.AdminUsers {
+		_, err := subscriptions.Add(Subscription{
+			ChatID:         adminID,
+			Origins:        query.Origins,
+			Destination:    query.Destination,
+			MaxPrice:       query.MaxPrice,
+			MonthsToSearch: query.MonthsToSearch,
+			DateFilter:     query.DateFilter,
+		})
 		if err != nil {
-			log.Printf("❌ Ошибка автоматического поиска: %v", err)
-			return
+			log.Printf("❌ Не удалось создать подписку для %d: %v", adminID, err)
 		}
+	}
+}
+
+func startScheduledSearch(delivery *AlertDelivery, scheduler *Scheduler, config *AppConfig, flightSearch *FlightSearch, history *PriceHistory) {
+	// Подписки с одинаковым расписанием проверяются вместе
+	scheduler.Start(func(subs []Subscription) {
+		log.Printf("🕙 Запуск автоматического поиска по расписанию, подписок: %d", len(subs))
 
-		// Отправляем результат в основной чат
-		for _, adminID := range config.AdminUsers {
-			bot.SendMessage(adminID, result)
+		// Все подписки сравниваются с историей до начала запуска, иначе
+		// подписка на тот же маршрут увидела бы цены, записанные предыдущей
+		runStartedAt := time.Now()
+		defaults := flightSearch.DefaultQuery()
+
+		for _, sub := range subs {
+			ctx, cancel := context.WithTimeout(context.Background(), searchTimeout)
+			result, err := flightSearch.Search(ctx, sub.Query(defaults, scheduler.subscriptions.Settings(sub.ChatID)))
+			cancel()
+			if err != nil {
+				log.Printf("❌ Ошибка автоматического поиска по подписке #%d: %v", sub.ID, err)
+				continue
+			}
+
+			// Уведомляем только о значимом снижении цен относительно истории
+			flights := append(append([]Flight{}, result.Outbound...), result.Return...)
+			alerts := DetectPriceDrops(history, flights, runStartedAt, config.AlertThresholds)
+			if len(alerts) == 0 {
+				log.Printf("ℹ️ Подписка #%d: значимых снижений цен нет, уведомление не отправляется", sub.ID)
+				continue
+			}
+
+			delivery.Deliver(sub, alerts, time.Now())
 		}
 	})
 
-	// Для теста: каждые 6 часов
-
-	c.Start()
 	log.Println("📅 Планировщик запущен")
 }
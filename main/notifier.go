@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// NotificationKind - повод уведомления, от него зависят заголовок и звук
+type NotificationKind string
+
+const (
+	NotifyAlert  NotificationKind = "alert"  // Снижение цен, отправлено сразу
+	NotifyUrgent NotificationKind = "urgent" // Срочное, приходит со звуком всегда
+	NotifyQuiet  NotificationKind = "quiet"  // Накопилось за тихие часы
+	NotifyDigest NotificationKind = "digest" // Дайджест по расписанию
+)
+
+// Notification - уведомление о снижении цен для одного чата, которое
+// каждый канал доставки оформляет по-своему
+type Notification struct {
+	ChatID   int64
+	ThreadID int // Тема форума подписки, 0 - без темы
+	Kind     NotificationKind
+	Alerts   []PriceAlert
+}
+
+// Title возвращает значок и заголовок уведомления
+func (n Notification) Title() (icon string, title string) {
+	switch n.Kind {
+	case NotifyUrgent:
+		return "🚨", "СРОЧНО: ЦЕНЫ РЕЗКО СНИЗИЛИСЬ!"
+	case NotifyQuiet:
+		return "🌙", "ЦЕНЫ СНИЗИЛИСЬ, ПОКА ВЫ ОТДЫХАЛИ"
+	case NotifyDigest:
+		return "📬", "ДАЙДЖЕСТ ЦЕН"
+	default:
+		return "📉", "ЦЕНЫ СНИЗИЛИСЬ!"
+	}
+}
+
+// Silent сообщает, что уведомление можно доставить без звука
+func (n Notification) Silent() bool {
+	return n.Kind == NotifyDigest
+}
+
+// Notifier - канал доставки уведомлений: Telegram, почта, вебхук.
+// Временные сбои доставки каналы повторяют сами.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// notifierNames - каналы уведомлений, которые можно включить в NOTIFIERS
+var notifierNames = []string{"telegram", "channel", "email", "slack", "discord", "webhook"}
+
+// newNotifiers создает включенные в конфигурации каналы уведомлений по названиям
+func newNotifiers(config *AppConfig, api *tgbotapi.BotAPI) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier)
+
+	// Один HTTP-клиент на все вебхуки, чтобы переиспользовать соединения
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	for _, name := range config.Notifiers {
+		var notifier Notifier
+
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+			continue
+		case "telegram":
+			notifier = NewTelegramNotifier(api, config.NotifyRetry)
+		case "channel":
+			channel, err := NewTelegramChannelNotifier(api, config.TelegramChatID, config.TelegramThreadID, config.NotifyRetry)
+			if err != nil {
+				return nil, err
+			}
+			notifier = channel
+		case "email":
+			email, err := NewEmailNotifier(config.Email, config.NotifyRetry)
+			if err != nil {
+				return nil, err
+			}
+			notifier = email
+		case "slack":
+			if config.SlackWebhookURL == "" {
+				return nil, fmt.Errorf("для канала slack нужен SLACK_WEBHOOK_URL")
+			}
+			notifier = NewSlackNotifier(client, config.SlackWebhookURL, config.NotifyRetry)
+		case "discord":
+			if config.DiscordWebhookURL == "" {
+				return nil, fmt.Errorf("для канала discord нужен DISCORD_WEBHOOK_URL")
+			}
+			notifier = NewDiscordNotifier(client, config.DiscordWebhookURL, config.NotifyRetry)
+		case "webhook":
+			if config.WebhookURL == "" {
+				return nil, fmt.Errorf("для канала webhook нужен WEBHOOK_URL")
+			}
+			notifier = NewWebhookNotifier(client, config.WebhookURL, config.WebhookFormat, config.NotifyRetry)
+		default:
+			return nil, fmt.Errorf("неизвестный канал уведомлений: %s, доступны: %s", name, strings.Join(notifierNames, ", "))
+		}
+
+		notifiers[name] = notifier
+	}
+
+	if len(notifiers) == 0 {
+		return nil, fmt.Errorf("не настроен ни один канал уведомлений")
+	}
+	for _, name := range config.DefaultSinks {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" && notifiers[name] == nil {
+			return nil, fmt.Errorf("канал по умолчанию %s не включен в NOTIFIERS", name)
+		}
+	}
+	return notifiers, nil
+}
+
+// NotificationFormat - в каком виде канал получает текст уведомления
+type NotificationFormat string
+
+const (
+	FormatHTML     NotificationFormat = "html"
+	FormatMarkdown NotificationFormat = "markdown"
+	FormatText     NotificationFormat = "text"
+	FormatJSON     NotificationFormat = "json"
+)
+
+// ParseNotificationFormat проверяет название формата
+func ParseNotificationFormat(value string) (NotificationFormat, error) {
+	switch format := NotificationFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case FormatHTML, FormatMarkdown, FormatText, FormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("неизвестный формат уведомлений: %s, доступны: html, markdown, text, json", value)
+}
+
+// FormatNotification оформляет уведомление в нужном формате
+func FormatNotification(n Notification, format NotificationFormat) (string, error) {
+	icon, title := n.Title()
+	switch format {
+	case FormatHTML:
+		return FormatAlertsHTML(fmt.Sprintf("%s <b>%s</b>", icon, html.EscapeString(title)), n.Alerts), nil
+	case FormatMarkdown:
+		return formatAlertsMarkdown(n, commonMarkdown), nil
+	case FormatText:
+		return formatAlertsText(icon+" "+title, n.Alerts), nil
+	case FormatJSON:
+		data, err := json.Marshal(newNotificationPayload(n))
+		return string(data), err
+	}
+	return "", fmt.Errorf("неизвестный формат уведомлений: %s", format)
+}
+
+// markdownDialect - разметка Markdown конкретного получателя:
+// Slack понимает только свой вариант mrkdwn
+type markdownDialect struct {
+	Bold   string
+	Strike string
+	Link   func(text, url string) string
+}
+
+var commonMarkdown = markdownDialect{
+	Bold:   "**",
+	Strike: "~~",
+	Link:   func(text, url string) string { return fmt.Sprintf("[%s](%s)", text, url) },
+}
+
+var slackMarkdown = markdownDialect{
+	Bold:   "*",
+	Strike: "~",
+	Link:   func(text, url string) string { return fmt.Sprintf("<%s|%s>", url, text) },
+}
+
+func formatAlertsMarkdown(n Notification, md markdownDialect) string {
+	icon, title := n.Title()
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s%s%s\n\n", icon, md.Bold, title, md.Bold))
+	for _, alert := range n.Alerts {
+		flight := alert.Flight
+		sb.WriteString(fmt.Sprintf("🛫 %s%s → %s%s, %s %s %s\n",
+			md.Bold, getCityName(flight.Origin), getCityName(flight.Destination), md.Bold,
+			flight.DepartureDate, flight.DayOfWeek, flight.DepartureTime))
+		sb.WriteString(fmt.Sprintf("%s%d₽%s → %s%d₽%s (−%d%%) | %s | %s | %s %s\n",
+			md.Strike, alert.OldPrice, md.Strike, md.Bold, flight.Price, md.Bold, alert.DropPercent(),
+			formatDuration(flight.Duration), getTransfersText(flight.Transfers), flight.Airline,
+			md.Link("🎫 билет", flight.Link)))
+		if alert.Kind == AlertAllTimeLow {
+			sb.WriteString(fmt.Sprintf("🏆 _Новый исторический минимум (был %d₽)_\n", alert.LowPrice))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func formatAlertsText(title string, alerts []PriceAlert) string {
+	var sb strings.Builder
+	sb.WriteString(title + "\n\n")
+	for _, alert := range alerts {
+		flight := alert.Flight
+		sb.WriteString(fmt.Sprintf("%s → %s, %s %s %s\n",
+			getCityName(flight.Origin), getCityName(flight.Destination),
+			flight.DepartureDate, flight.DayOfWeek, flight.DepartureTime))
+		sb.WriteString(fmt.Sprintf("  %d₽ → %d₽ (−%d%%) | %s | %s | %s\n",
+			alert.OldPrice, flight.Price, alert.DropPercent(),
+			formatDuration(flight.Duration), getTransfersText(flight.Transfers), flight.Airline))
+		if alert.Kind == AlertAllTimeLow {
+			sb.WriteString(fmt.Sprintf("  Новый исторический минимум (был %d₽)\n", alert.LowPrice))
+		}
+		sb.WriteString("  " + flight.Link + "\n\n")
+	}
+	return sb.String()
+}
+
+// notificationPayload - уведомление в формате JSON для вебхуков
+type notificationPayload struct {
+	ChatID int64              `json:"chat_id"`
+	Kind   NotificationKind   `json:"kind"`
+	Title  string             `json:"title"`
+	Alerts []alertPayloadItem `json:"alerts"`
+}
+
+type alertPayloadItem struct {
+	Kind        AlertKind `json:"kind"`
+	Urgent      bool      `json:"urgent"`
+	Origin      string    `json:"origin"`
+	Destination string    `json:"destination"`
+	DepartureAt time.Time `json:"departure_at"`
+	Airline     string    `json:"airline"`
+	Transfers   int       `json:"transfers"`
+	Duration    int       `json:"duration"`
+	Price       int       `json:"price"`
+	OldPrice    int       `json:"old_price"`
+	LowPrice    int       `json:"low_price"`
+	DropPercent int       `json:"drop_percent"`
+	Link        string    `json:"link"`
+}
+
+func newNotificationPayload(n Notification) notificationPayload {
+	_, title := n.Title()
+	payload := notificationPayload{ChatID: n.ChatID, Kind: n.Kind, Title: title, Alerts: []alertPayloadItem{}}
+	for _, alert := range n.Alerts {
+		flight := alert.Flight
+		payload.Alerts = append(payload.Alerts, alertPayloadItem{
+			Kind:        alert.Kind,
+			Urgent:      alert.Urgent,
+			Origin:      flight.Origin,
+			Destination: flight.Destination,
+			DepartureAt: flight.DepartureAt,
+			Airline:     flight.Airline,
+			Transfers:   flight.Transfers,
+			Duration:    flight.Duration,
+			Price:       flight.Price,
+			OldPrice:    alert.OldPrice,
+			LowPrice:    alert.LowPrice,
+			DropPercent: alert.DropPercent(),
+			Link:        flight.Link,
+		})
+	}
+	return payload
+}
+
+// NotifyError - ошибка доставки уведомления
+type NotifyError struct {
+	Notifier  string
+	Temporary bool // Сбой сети, 429 или 5xx: доставку стоит повторить
+	Err       error
+}
+
+func (e *NotifyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Notifier, e.Err)
+}
+
+func (e *NotifyError) Unwrap() error {
+	return e.Err
+}
+
+func (e *NotifyError) Retryable() bool {
+	return e.Temporary
+}
+
+// postWebhook отправляет тело на адрес вебхука с повтором временных сбоев
+func postWebhook(ctx context.Context, client *http.Client, retry RetryPolicy, notifier, url, contentType string, body []byte) error {
+	return retry.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return &NotifyError{Notifier: notifier, Err: err}
+		}
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return &NotifyError{Notifier: notifier, Temporary: ctx.Err() == nil, Err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return &NotifyError{
+				Notifier:  notifier,
+				Temporary: resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+				Err:       fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(message))),
+			}
+		}
+		return nil
+	})
+}
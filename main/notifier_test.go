@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// testRetry - быстрые повторы для тестов каналов уведомлений
+var testRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+// webhookRecorder - заглушка вебхука: первые failures запросов получают
+// status, остальные - 200; тела успешных запросов запоминаются
+type webhookRecorder struct {
+	server *httptest.Server
+
+	mu           sync.Mutex
+	failures     int
+	status       int
+	attempts     int
+	bodies       [][]byte
+	contentTypes []string
+}
+
+func newWebhookRecorder(t *testing.T, failures, status int) *webhookRecorder {
+	recorder := &webhookRecorder{failures: failures, status: status}
+	recorder.server = httptest.NewServer(http.HandlerFunc(recorder.handle))
+	t.Cleanup(recorder.server.Close)
+	return recorder
+}
+
+func (r *webhookRecorder) handle(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+	if r.attempts <= r.failures {
+		http.Error(w, "unavailable", r.status)
+		return
+	}
+	r.bodies = append(r.bodies, body)
+	r.contentTypes = append(r.contentTypes, req.Header.Get("Content-Type"))
+}
+
+func (r *webhookRecorder) Attempts() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.attempts
+}
+
+func (r *webhookRecorder) Bodies() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([][]byte(nil), r.bodies...)
+}
+
+func testNotification(alerts ...PriceAlert) Notification {
+	return Notification{ChatID: 42, Kind: NotifyAlert, Alerts: alerts}
+}
+
+func TestSlackNotifierRetriesServerErrors(t *testing.T) {
+	recorder := newWebhookRecorder(t, 2, http.StatusServiceUnavailable)
+	notifier := NewSlackNotifier(recorder.server.Client(), recorder.server.URL, testRetry)
+
+	if err := notifier.Notify(context.Background(), testNotification(testAlert(10000))); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if recorder.Attempts() != 3 {
+		t.Errorf("попыток %d, ожидалось 3: две ошибки 503 и успех", recorder.Attempts())
+	}
+
+	bodies := recorder.Bodies()
+	if len(bodies) != 1 {
+		t.Fatalf("доставлено сообщений: %d", len(bodies))
+	}
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("тело не JSON: %v: %s", err, bodies[0])
+	}
+	// Slack mrkdwn: одинарные звёздочки и тильды, ссылки <url|текст>
+	for _, want := range []string{"📉 *ЦЕНЫ СНИЗИЛИСЬ!*", "~12000₽~ → *10000₽*", "<https://example.com|🎫 билет>"} {
+		if !strings.Contains(payload.Text, want) {
+			t.Errorf("в сообщении Slack нет %q:\n%s", want, payload.Text)
+		}
+	}
+}
+
+func TestSlackNotifierGivesUpOnServerErrors(t *testing.T) {
+	recorder := newWebhookRecorder(t, 10, http.StatusBadGateway)
+	notifier := NewSlackNotifier(recorder.server.Client(), recorder.server.URL, testRetry)
+
+	err := notifier.Notify(context.Background(), testNotification(testAlert(10000)))
+	var notifyErr *NotifyError
+	if !errors.As(err, &notifyErr) || !notifyErr.Retryable() {
+		t.Fatalf("ожидалась временная NotifyError, получено %v", err)
+	}
+	if recorder.Attempts() != testRetry.MaxAttempts {
+		t.Errorf("попыток %d, ожидалось %d", recorder.Attempts(), testRetry.MaxAttempts)
+	}
+}
+
+func TestDiscordNotifierSplitsLongMessages(t *testing.T) {
+	recorder := newWebhookRecorder(t, 1, http.StatusInternalServerError)
+	notifier := NewDiscordNotifier(recorder.server.Client(), recorder.server.URL, testRetry)
+
+	var alerts []PriceAlert
+	for i := 0; i < 30; i++ {
+		alerts = append(alerts, testAlert(10000+i))
+	}
+	if err := notifier.Notify(context.Background(), testNotification(alerts...)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	bodies := recorder.Bodies()
+	if len(bodies) < 2 {
+		t.Fatalf("длинное уведомление ушло %d сообщениями, ожидалось несколько", len(bodies))
+	}
+	var text strings.Builder
+	for _, body := range bodies {
+		var payload struct {
+			Content         string `json:"content"`
+			AllowedMentions struct {
+				Parse []string `json:"parse"`
+			} `json:"allowed_mentions"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("тело не JSON: %v: %s", err, body)
+		}
+		if length := utf8.RuneCountInString(payload.Content); length > discordMessageLength {
+			t.Errorf("сообщение Discord длиной %d больше %d", length, discordMessageLength)
+		}
+		if payload.AllowedMentions.Parse == nil || len(payload.AllowedMentions.Parse) != 0 {
+			t.Errorf("упоминания не отключены: %s", body)
+		}
+		text.WriteString(payload.Content)
+	}
+
+	// Discord понимает обычный Markdown
+	for _, want := range []string{"📉 **ЦЕНЫ СНИЗИЛИСЬ!**", "~~12000₽~~ → **10000₽**", "[🎫 билет](https://example.com)", "**10029₽**"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("в сообщениях Discord нет %q", want)
+		}
+	}
+}
+
+func TestWebhookNotifierSendsJSONPayload(t *testing.T) {
+	recorder := newWebhookRecorder(t, 1, http.StatusTooManyRequests)
+	notifier := NewWebhookNotifier(recorder.server.Client(), recorder.server.URL, FormatJSON, testRetry)
+
+	alert := testAlert(10000)
+	alert.Kind, alert.LowPrice, alert.Urgent = AlertAllTimeLow, 11000, true
+	if err := notifier.Notify(context.Background(), testNotification(alert)); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if recorder.Attempts() != 2 {
+		t.Errorf("попыток %d, ожидалось 2: 429 повторяется", recorder.Attempts())
+	}
+	recorder.mu.Lock()
+	contentType := recorder.contentTypes[0]
+	recorder.mu.Unlock()
+	if contentType != "application/json" {
+		t.Errorf("Content-Type %q", contentType)
+	}
+
+	var payload struct {
+		ChatID int64  `json:"chat_id"`
+		Kind   string `json:"kind"`
+		Title  string `json:"title"`
+		Alerts []struct {
+			Kind        string    `json:"kind"`
+			Urgent      bool      `json:"urgent"`
+			Origin      string    `json:"origin"`
+			Destination string    `json:"destination"`
+			DepartureAt time.Time `json:"departure_at"`
+			Airline     string    `json:"airline"`
+			Price       int       `json:"price"`
+			OldPrice    int       `json:"old_price"`
+			LowPrice    int       `json:"low_price"`
+			DropPercent int       `json:"drop_percent"`
+			Link        string    `json:"link"`
+		} `json:"alerts"`
+	}
+	if err := json.Unmarshal(recorder.Bodies()[0], &payload); err != nil {
+		t.Fatalf("тело не JSON: %v", err)
+	}
+	if payload.ChatID != 42 || payload.Kind != string(NotifyAlert) || payload.Title != "ЦЕНЫ СНИЗИЛИСЬ!" || len(payload.Alerts) != 1 {
+		t.Fatalf("неожиданное уведомление: %+v", payload)
+	}
+	got := payload.Alerts[0]
+	if got.Kind != "low" || !got.Urgent || got.Origin != "OVB" || got.Destination != "AER" || got.Airline != "S7" ||
+		got.Price != 10000 || got.OldPrice != 12000 || got.LowPrice != 11000 || got.DropPercent != alert.DropPercent() ||
+		!got.DepartureAt.Equal(alert.Flight.DepartureAt) || got.Link != "https://example.com" {
+		t.Errorf("неожиданный рейс в уведомлении: %+v", got)
+	}
+}
+
+func TestWebhookNotifierDoesNotRetryClientErrors(t *testing.T) {
+	recorder := newWebhookRecorder(t, 10, http.StatusBadRequest)
+	notifier := NewWebhookNotifier(recorder.server.Client(), recorder.server.URL, FormatText, testRetry)
+
+	err := notifier.Notify(context.Background(), testNotification(testAlert(10000)))
+	var notifyErr *NotifyError
+	if !errors.As(err, &notifyErr) || notifyErr.Retryable() {
+		t.Fatalf("ожидалась постоянная NotifyError, получено %v", err)
+	}
+	if recorder.Attempts() != 1 {
+		t.Errorf("ошибка 400 повторена: попыток %d", recorder.Attempts())
+	}
+}
+
+// smtpStandIn - минимальный SMTP-сервер: принимает письма без авторизации
+// и TLS, первые rejects писем отклоняет временной ошибкой 451
+type smtpStandIn struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	rejects  int
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+func newSMTPStandIn(t *testing.T, rejects int) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &smtpStandIn{listener: listener, rejects: rejects}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *smtpStandIn) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStandIn) Messages() []smtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]smtpMessage(nil), s.messages...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	var message smtpMessage
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250-localhost")
+			text.PrintfLine("250 8BITMIME")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.mu.Lock()
+			reject := s.rejects > 0
+			if reject {
+				s.rejects--
+			}
+			s.mu.Unlock()
+			if reject {
+				text.PrintfLine("451 try again later")
+				continue
+			}
+			message = smtpMessage{From: smtpAddress(line)}
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			message.To = append(message.To, smtpAddress(line))
+			text.PrintfLine("250 OK")
+		case command == "DATA":
+			text.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			message.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+// smtpAddress достает адрес из "MAIL FROM:<a@b> BODY=8BITMIME"
+func smtpAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestEmailNotifierSendsMessage(t *testing.T) {
+	server := newSMTPStandIn(t, 1)
+	notifier, err := NewEmailNotifier(EmailConfig{
+		Host:   "127.0.0.1",
+		Port:   server.Port(),
+		From:   "bot@example.com",
+		To:     []string{"alice@example.com", " ", "bob@example.com"},
+		Format: FormatText,
+	}, testRetry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := notifier.Notify(context.Background(), testNotification(testAlert(10000))); err != nil {
+		t.Fatalf("Notify после временной ошибки 451: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("принято писем: %d", len(messages))
+	}
+	message := messages[0]
+	if message.From != "bot@example.com" || strings.Join(message.To, ",") != "alice@example.com,bob@example.com" {
+		t.Errorf("конверт письма: %+v", message)
+	}
+
+	parsed, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(message.Data)))
+	if err != nil {
+		t.Fatalf("разбор письма: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "✈️ ЦЕНЫ СНИЗИЛИСЬ!" {
+		t.Errorf("тема письма %q (%v)", subject, err)
+	}
+	if contentType := parsed.Header.Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type %q", contentType)
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	for _, want := range []string{"12000₽ → 10000₽", "https://example.com"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("в письме нет %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// EmailConfig - настройки отправки уведомлений по почте через SMTP
+type EmailConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	Format   NotificationFormat // text или html
+}
+
+// EmailNotifier отправляет уведомления письмом на адреса из настроек
+type EmailNotifier struct {
+	config EmailConfig
+	retry  RetryPolicy
+}
+
+func NewEmailNotifier(config EmailConfig, retry RetryPolicy) (*EmailNotifier, error) {
+	if config.Format != FormatText && config.Format != FormatHTML {
+		return nil, fmt.Errorf("письма поддерживают только форматы text и html, а не %s", config.Format)
+	}
+	var to []string
+	for _, address := range config.To {
+		if address = strings.TrimSpace(address); address != "" {
+			to = append(to, address)
+		}
+	}
+	config.To = to
+
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("для почты нужны SMTP_HOST, SMTP_FROM и SMTP_TO")
+	}
+	return &EmailNotifier{config: config, retry: retry}, nil
+}
+
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := FormatNotification(n, e.config.Format)
+	if err != nil {
+		return err
+	}
+	_, title := n.Title()
+
+	contentType := "text/plain"
+	if e.config.Format == FormatHTML {
+		contentType = "text/html"
+		body = strings.ReplaceAll(body, "\n", "<br>\r\n")
+	}
+
+	var message strings.Builder
+	message.WriteString("From: " + e.config.From + "\r\n")
+	message.WriteString("To: " + strings.Join(e.config.To, ", ") + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", "✈️ "+title) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: " + contentType + "; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	message.WriteString(body)
+
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	}
+	addr := net.JoinHostPort(e.config.Host, fmt.Sprint(e.config.Port))
+
+	return e.retry.Do(ctx, func() error {
+		err := smtp.SendMail(addr, auth, e.config.From, e.config.To, []byte(message.String()))
+		return e.error(err)
+	})
+}
+
+// error классифицирует ошибку SMTP: коды 4xx и сетевые сбои временные
+func (e *EmailNotifier) error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return &NotifyError{Notifier: e.Name(), Temporary: smtpErr.Code >= 400 && smtpErr.Code < 500, Err: err}
+	}
+	var netErr net.Error
+	return &NotifyError{Notifier: e.Name(), Temporary: errors.As(err, &netErr), Err: err}
+}
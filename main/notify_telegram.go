@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// TelegramNotifier отправляет уведомления через бота: в чат подписки или,
+// для канала рассылки, в заданный канал или группу
+type TelegramNotifier struct {
+	api   *tgbotapi.BotAPI
+	retry RetryPolicy
+	name  string
+
+	// Канал или группа рассылки: "-1001234567890" или "@channel".
+	// Пустое значение - чат и тема подписки.
+	chat   string
+	thread int
+}
+
+func NewTelegramNotifier(api *tgbotapi.BotAPI, retry RetryPolicy) *TelegramNotifier {
+	return &TelegramNotifier{api: api, retry: retry, name: "telegram"}
+}
+
+// NewTelegramChannelNotifier отправляет все уведомления в общий канал или группу,
+// при thread != 0 - в тему форума
+func NewTelegramChannelNotifier(api *tgbotapi.BotAPI, chat string, thread int, retry RetryPolicy) (*TelegramNotifier, error) {
+	chat = strings.TrimSpace(chat)
+	if chat == "" {
+		return nil, fmt.Errorf("для канала channel нужен TELEGRAM_CHAT_ID")
+	}
+	if _, err := strconv.ParseInt(chat, 10, 64); err != nil && !strings.HasPrefix(chat, "@") {
+		return nil, fmt.Errorf("некорректный TELEGRAM_CHAT_ID %q, нужен номер чата или @канал", chat)
+	}
+	return &TelegramNotifier{api: api, retry: retry, name: "channel", chat: chat, thread: thread}, nil
+}
+
+func (t *TelegramNotifier) Name() string {
+	return t.name
+}
+
+// Notify отправляет уведомление в HTML. Длинный текст уходит несколькими
+// сообщениями, при сбое повторяется только неотправленная часть.
+func (t *TelegramNotifier) Notify(ctx context.Context, n Notification) error {
+	text, err := FormatNotification(n, FormatHTML)
+	if err != nil {
+		return err
+	}
+
+	thread := n.ThreadID
+	if t.chat != "" {
+		thread = t.thread
+	}
+
+	for _, part := range SplitMessageHTML(text) {
+		msg := tgbotapi.NewMessage(n.ChatID, part)
+		if t.chat != "" {
+			msg = t.channelMessage(part)
+		}
+		msg.ParseMode = "HTML"
+		msg.DisableWebPagePreview = true
+		msg.DisableNotification = n.Silent()
+
+		err := t.retry.Do(ctx, func() error {
+			_, err := sendTelegramMessage(t.api, msg, thread)
+			return t.error(err)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TelegramNotifier) channelMessage(text string) tgbotapi.MessageConfig {
+	if id, err := strconv.ParseInt(t.chat, 10, 64); err == nil {
+		return tgbotapi.NewMessage(id, text)
+	}
+	return tgbotapi.NewMessageToChannel(t.chat, text)
+}
+
+// error классифицирует ошибку Telegram: 429 и 5xx временные, как и сетевые сбои
+func (t *TelegramNotifier) error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		return &NotifyError{
+			Notifier:  t.Name(),
+			Temporary: apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500,
+			Err:       err,
+		}
+	}
+	return &NotifyError{Notifier: t.Name(), Temporary: true, Err: err}
+}
+
+// sendTelegramMessage отправляет сообщение, при thread != 0 - в тему форума.
+// telegram-bot-api v5.5.1 не знает message_thread_id, поэтому такой запрос
+// собирается вручную.
+func sendTelegramMessage(api *tgbotapi.BotAPI, msg tgbotapi.MessageConfig, thread int) (tgbotapi.Message, error) {
+	if thread == 0 {
+		return api.Send(msg)
+	}
+
+	params := make(tgbotapi.Params)
+	if err := params.AddFirstValid("chat_id", msg.ChatID, msg.ChannelUsername); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	params.AddNonZero("message_thread_id", thread)
+	params["text"] = msg.Text
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	params.AddBool("disable_web_page_preview", msg.DisableWebPagePreview)
+	params.AddBool("disable_notification", msg.DisableNotification)
+	params.AddNonZero("reply_to_message_id", msg.ReplyToMessageID)
+	if err := params.AddInterface("reply_markup", msg.ReplyMarkup); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	resp, err := api.MakeRequest("sendMessage", params)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var sent tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &sent)
+	return sent, err
+}
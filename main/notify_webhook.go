@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// discordMessageLength - ограничение Discord на длину сообщения
+const discordMessageLength = 2000
+
+// SlackNotifier отправляет уведомления во входящий вебхук Slack
+type SlackNotifier struct {
+	client *http.Client
+	url    string
+	retry  RetryPolicy
+}
+
+func NewSlackNotifier(client *http.Client, url string, retry RetryPolicy) *SlackNotifier {
+	return &SlackNotifier{client: client, url: url, retry: retry}
+}
+
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (s *SlackNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(map[string]string{"text": formatAlertsMarkdown(n, slackMarkdown)})
+	if err != nil {
+		return err
+	}
+	return postWebhook(ctx, s.client, s.retry, s.Name(), s.url, "application/json", body)
+}
+
+// DiscordNotifier отправляет уведомления во входящий вебхук Discord
+type DiscordNotifier struct {
+	client *http.Client
+	url    string
+	retry  RetryPolicy
+}
+
+func NewDiscordNotifier(client *http.Client, url string, retry RetryPolicy) *DiscordNotifier {
+	return &DiscordNotifier{client: client, url: url, retry: retry}
+}
+
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Notify отправляет уведомление в Markdown, длинный текст - несколькими сообщениями
+func (d *DiscordNotifier) Notify(ctx context.Context, n Notification) error {
+	for _, part := range splitLines(formatAlertsMarkdown(n, commonMarkdown), discordMessageLength) {
+		body, err := json.Marshal(map[string]any{
+			"content":          part,
+			"allowed_mentions": map[string]any{"parse": []string{}},
+		})
+		if err != nil {
+			return err
+		}
+		if err := postWebhook(ctx, d.client, d.retry, d.Name(), d.url, "application/json", body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookNotifier отправляет уведомления POST-запросом на произвольный адрес:
+// в формате json - структурой с рейсами, в остальных - текстом
+type WebhookNotifier struct {
+	client *http.Client
+	url    string
+	format NotificationFormat
+	retry  RetryPolicy
+}
+
+func NewWebhookNotifier(client *http.Client, url string, format NotificationFormat, retry RetryPolicy) *WebhookNotifier {
+	return &WebhookNotifier{client: client, url: url, format: format, retry: retry}
+}
+
+func (w *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := FormatNotification(n, w.format)
+	if err != nil {
+		return err
+	}
+
+	contentType := map[NotificationFormat]string{
+		FormatJSON:     "application/json",
+		FormatHTML:     "text/html; charset=utf-8",
+		FormatMarkdown: "text/markdown; charset=utf-8",
+		FormatText:     "text/plain; charset=utf-8",
+	}[w.format]
+	return postWebhook(ctx, w.client, w.retry, w.Name(), w.url, contentType, []byte(body))
+}
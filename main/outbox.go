@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueuedAlert - уведомление, отложенное до конца тихих часов или до дайджеста
+type QueuedAlert struct {
+	SubscriptionID int        `json:"subscription_id"`
+	Alert          PriceAlert `json:"alert"`
+	QueuedAt       time.Time  `json:"queued_at"`
+	Sent           []string   `json:"sent,omitempty"` // Каналы, в которые уведомление уже доставлено
+}
+
+// key - подписка, рейс и цена: по нему доставка отмечается у того же уведомления,
+// даже если пока шла отправка, очередь пополнилась
+func (q QueuedAlert) key() string {
+	return fmt.Sprintf("%d|%s|%d", q.SubscriptionID, fareKey(q.Alert.Flight), q.Alert.Flight.Price)
+}
+
+// outboxRetention - сколько повторять доставку отложенного уведомления,
+// которое не удается отправить
+const outboxRetention = 24 * time.Hour
+
+// Outbox хранит отложенные уведомления чатов в JSON-файле, чтобы они
+// не терялись при перезапуске
+type Outbox struct {
+	mu      sync.Mutex
+	path    string
+	Pending map[int64][]QueuedAlert `json:"pending"`
+}
+
+func NewOutbox(path string) (*Outbox, error) {
+	outbox := &Outbox{
+		path:    path,
+		Pending: make(map[int64][]QueuedAlert),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return outbox, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("чтение отложенных уведомлений: %w", err)
+	}
+	if err := json.Unmarshal(data, outbox); err != nil {
+		return nil, fmt.Errorf("разбор отложенных уведомлений: %w", err)
+	}
+	if outbox.Pending == nil {
+		outbox.Pending = make(map[int64][]QueuedAlert)
+	}
+	return outbox, nil
+}
+
+// Queue откладывает уведомления чата. Уведомление о рейсе, который уже ждет
+// в очереди подписки, заменяет прежнее; если цена изменилась, оно заново
+// рассылается во все каналы.
+func (o *Outbox) Queue(chatID int64, subscriptionID int, alerts []PriceAlert, now time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := o.Pending[chatID]
+	for _, alert := range alerts {
+		item := QueuedAlert{SubscriptionID: subscriptionID, Alert: alert, QueuedAt: now}
+
+		replaced := false
+		for i, queued := range pending {
+			if queued.SubscriptionID != subscriptionID || fareKey(queued.Alert.Flight) != fareKey(alert.Flight) {
+				continue
+			}
+			item.QueuedAt = queued.QueuedAt
+			if queued.Alert.Flight.Price == alert.Flight.Price {
+				item.Sent = queued.Sent
+			}
+			pending[i], replaced = item, true
+			break
+		}
+		if !replaced {
+			pending = append(pending, item)
+		}
+	}
+	o.Pending[chatID] = pending
+	return o.save()
+}
+
+// Chats возвращает чаты, у которых есть отложенные уведомления
+func (o *Outbox) Chats() []int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	chats := make([]int64, 0, len(o.Pending))
+	for chatID := range o.Pending {
+		chats = append(chats, chatID)
+	}
+	return chats
+}
+
+// Oldest возвращает время самого раннего отложенного уведомления чата
+func (o *Outbox) Oldest(chatID int64) (time.Time, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := o.Pending[chatID]
+	if len(pending) == 0 {
+		return time.Time{}, false
+	}
+	return pending[0].QueuedAt, true
+}
+
+// Queued возвращает копию отложенных уведомлений чата
+func (o *Outbox) Queued(chatID int64) []QueuedAlert {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]QueuedAlert(nil), o.Pending[chatID]...)
+}
+
+// Update заменяет отложенные уведомления чата результатом change и сохраняет их
+func (o *Outbox) Update(chatID int64, change func(pending []QueuedAlert) []QueuedAlert) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if pending := change(o.Pending[chatID]); len(pending) > 0 {
+		o.Pending[chatID] = pending
+	} else {
+		delete(o.Pending, chatID)
+	}
+	return o.save()
+}
+
+// latestAlerts оставляет по одному маршруту и дате самое позднее уведомление
+// и сортирует уведомления по величине снижения
+func latestAlerts(queue []PriceAlert) []PriceAlert {
+	latest := make(map[string]int)
+	var alerts []PriceAlert
+	for _, alert := range queue {
+		flight := alert.Flight
+		key := routeDateKey(flight.Origin, flight.Destination, flight.DepartureAt.Format("2006-01-02"))
+		if i, exists := latest[key]; exists {
+			alerts[i] = alert
+			continue
+		}
+		latest[key] = len(alerts)
+		alerts = append(alerts, alert)
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].DropPercent() > alerts[j].DropPercent()
+	})
+	return alerts
+}
+
+// save атомарно перезаписывает файл. Вызывается под блокировкой.
+func (o *Outbox) save() error {
+	if err := os.MkdirAll(filepath.Dir(o.path), 0o755); err != nil {
+		return fmt.Errorf("создание каталога отложенных уведомлений: %w", err)
+	}
+
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := o.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("запись отложенных уведомлений: %w", err)
+	}
+	return os.Rename(tmpPath, o.path)
+}
+
+// notifyTimeout ограничивает доставку одного уведомления вместе с повторами
+const notifyTimeout = 2 * time.Minute
+
+// AlertDelivery решает, когда и как отправить уведомления чату: сразу,
+// после тихих часов или в дайджесте, - и рассылает их по каналам подписки.
+// Срочные уведомления отправляются сразу и со звуком.
+type AlertDelivery struct {
+	notifiers     map[string]Notifier
+	defaultSinks  []string
+	subscriptions *SubscriptionStore
+	scheduler     *Scheduler
+	outbox        *Outbox
+	ledger        *NotificationLedger
+}
+
+func NewAlertDelivery(config *AppConfig, notifiers map[string]Notifier, subscriptions *SubscriptionStore, scheduler *Scheduler, outbox *Outbox, ledger *NotificationLedger) *AlertDelivery {
+	return &AlertDelivery{
+		notifiers:     notifiers,
+		defaultSinks:  config.DefaultSinks,
+		subscriptions: subscriptions,
+		scheduler:     scheduler,
+		outbox:        outbox,
+		ledger:        ledger,
+	}
+}
+
+// Deliver отправляет или откладывает уведомления подписки. Уведомления,
+// о которых чату уже сообщалось, отбрасываются. В журнал уведомления
+// попадают только после отправки хотя бы в один канал: неотправленные
+// будут повторены при следующем запуске, отложенные записывает send.
+func (d *AlertDelivery) Deliver(sub Subscription, alerts []PriceAlert, now time.Time) {
+	alerts = d.ledger.Fresh(sub.ChatID, alerts, now)
+	if len(alerts) == 0 {
+		log.Printf("ℹ️ Подписка #%d: обо всех снижениях цен уже сообщалось", sub.ID)
+		return
+	}
+
+	var urgent, regular []PriceAlert
+	for _, alert := range alerts {
+		if alert.Urgent {
+			urgent = append(urgent, alert)
+		} else {
+			regular = append(regular, alert)
+		}
+	}
+
+	sinks := d.sinks(sub)
+	if len(urgent) > 0 {
+		if d.notify(sinks, Notification{ChatID: sub.ChatID, ThreadID: sub.ThreadID, Kind: NotifyUrgent, Alerts: urgent}) {
+			d.record(sub.ChatID, urgent, now)
+		}
+	}
+	if len(regular) == 0 {
+		return
+	}
+
+	settings := d.subscriptions.Settings(sub.ChatID)
+	if settings.Digest == "" && !d.quiet(sub.ChatID, settings, now) {
+		if d.notify(sinks, Notification{ChatID: sub.ChatID, ThreadID: sub.ThreadID, Kind: NotifyAlert, Alerts: regular}) {
+			d.record(sub.ChatID, regular, now)
+		}
+		return
+	}
+
+	if err := d.outbox.Queue(sub.ChatID, sub.ID, regular, now); err != nil {
+		log.Printf("❌ Не удалось сохранить отложенные уведомления чата %d: %v", sub.ChatID, err)
+		return
+	}
+	log.Printf("🌙 Подписка #%d: уведомлений отложено: %d", sub.ID, len(regular))
+}
+
+// record запоминает доставленные уведомления в журнале
+func (d *AlertDelivery) record(chatID int64, alerts []PriceAlert, now time.Time) {
+	if err := d.ledger.Record(chatID, alerts, now); err != nil {
+		log.Printf("❌ Не удалось сохранить журнал уведомлений: %v", err)
+	}
+}
+
+// Run раз в минуту отправляет отложенные уведомления, время которых пришло
+func (d *AlertDelivery) Run() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		d.Flush(now)
+	}
+}
+
+// Flush отправляет дайджесты, время которых пришло, и уведомления,
+// накопившиеся за закончившиеся тихие часы
+func (d *AlertDelivery) Flush(now time.Time) {
+	for _, chatID := range d.outbox.Chats() {
+		settings := d.subscriptions.Settings(chatID)
+
+		if settings.Digest != "" {
+			if !d.digestDue(chatID, settings, now) {
+				continue
+			}
+			d.send(chatID, NotifyDigest, now)
+			continue
+		}
+
+		if !d.quiet(chatID, settings, now) {
+			d.send(chatID, NotifyQuiet, now)
+		}
+	}
+}
+
+// send рассылает отложенные уведомления чата: каждый канал получает
+// уведомления тех подписок, для которых он выбран. Уведомление остается
+// в очереди, пока не дойдет во все свои каналы или не устареет; отправленное
+// хотя бы в один канал записывается в журнал.
+func (d *AlertDelivery) send(chatID int64, kind NotificationKind, now time.Time) {
+	queued := d.outbox.Queued(chatID)
+	if len(queued) == 0 {
+		return
+	}
+
+	// Подписка могла быть удалена, пока уведомление ждало: тогда - каналы по умолчанию
+	subs := make(map[int]Subscription)
+	for _, sub := range d.subscriptions.ForChat(chatID) {
+		subs[sub.ID] = sub
+	}
+	subscription := func(item QueuedAlert) Subscription {
+		if sub, exists := subs[item.SubscriptionID]; exists {
+			return sub
+		}
+		return Subscription{ChatID: chatID}
+	}
+
+	// Уведомления в Telegram делятся еще и по темам форума, в которых созданы подписки
+	type target struct {
+		sink   string
+		thread int
+	}
+	byTarget := make(map[target][]QueuedAlert)
+	var order []target
+	for _, item := range queued {
+		sub := subscription(item)
+		for _, sink := range d.sinks(sub) {
+			if containsString(item.Sent, sink) {
+				continue
+			}
+			key := target{sink: sink, thread: sub.ThreadID}
+			if _, seen := byTarget[key]; !seen {
+				order = append(order, key)
+			}
+			byTarget[key] = append(byTarget[key], item)
+		}
+	}
+
+	delivered := make(map[string][]string) // Ключ уведомления -> каналы, куда оно дошло сейчас
+	var sent []PriceAlert
+	for _, key := range order {
+		items := byTarget[key]
+		alerts := make([]PriceAlert, 0, len(items))
+		for _, item := range items {
+			alerts = append(alerts, item.Alert)
+		}
+
+		n := Notification{ChatID: chatID, ThreadID: key.thread, Kind: kind, Alerts: latestAlerts(alerts)}
+		if !d.notify([]string{key.sink}, n) {
+			continue
+		}
+		for _, item := range items {
+			if len(delivered[item.key()]) == 0 {
+				sent = append(sent, item.Alert)
+			}
+			delivered[item.key()] = append(delivered[item.key()], key.sink)
+		}
+	}
+
+	err := d.outbox.Update(chatID, func(pending []QueuedAlert) []QueuedAlert {
+		var kept []QueuedAlert
+		for _, item := range pending {
+			item.Sent = append(item.Sent, delivered[item.key()]...)
+
+			remaining := 0
+			for _, sink := range d.sinks(subscription(item)) {
+				if !containsString(item.Sent, sink) {
+					remaining++
+				}
+			}
+			if remaining == 0 {
+				continue
+			}
+			if now.Sub(item.QueuedAt) >= outboxRetention {
+				log.Printf("⚠️ Отложенное уведомление чату %d не доставлено за %s, каналов без доставки: %d",
+					chatID, outboxRetention, remaining)
+				continue
+			}
+			kept = append(kept, item)
+		}
+		return kept
+	})
+	if err != nil {
+		log.Printf("❌ Не удалось сохранить отложенные уведомления чата %d: %v", chatID, err)
+	}
+	d.record(chatID, sent, now)
+}
+
+// sinks возвращает каналы уведомлений подписки
+func (d *AlertDelivery) sinks(sub Subscription) []string {
+	if len(sub.Sinks) > 0 {
+		return sub.Sinks
+	}
+	return d.defaultSinks
+}
+
+// notify отправляет уведомление в каждый из каналов; ошибка одного
+// канала не мешает остальным. Возвращает, доставлено ли уведомление
+// хотя бы в один канал.
+func (d *AlertDelivery) notify(sinks []string, n Notification) bool {
+	delivered := false
+	for _, sink := range sinks {
+		sink = strings.ToLower(strings.TrimSpace(sink))
+		notifier, exists := d.notifiers[sink]
+		if !exists {
+			log.Printf("⚠️ Канал уведомлений %s не включен, уведомление чату %d не отправлено", sink, n.ChatID)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := notifier.Notify(ctx, n)
+		cancel()
+		if err != nil {
+			log.Printf("❌ Не удалось отправить уведомление чату %d через %s: %v", n.ChatID, sink, err)
+			continue
+		}
+		delivered = true
+	}
+	return delivered
+}
+
+// quiet проверяет, идут ли в чате тихие часы
+func (d *AlertDelivery) quiet(chatID int64, settings ChatSettings, now time.Time) bool {
+	if settings.QuietHours.Empty() {
+		return false
+	}
+	return settings.QuietHours.Contains(now.In(d.scheduler.Location(chatID)).Format("15:04"))
+}
+
+// digestDue проверяет, наступило ли время дайджеста после первого отложенного уведомления
+func (d *AlertDelivery) digestDue(chatID int64, settings ChatSettings, now time.Time) bool {
+	_, timezone := d.scheduler.Schedule(Subscription{ChatID: chatID})
+	schedule, err := scheduleParser.Parse(withTimezone(settings.Digest, timezone))
+	if err != nil {
+		log.Printf("❌ Некорректное расписание дайджеста чата %d: %v", chatID, err)
+		return false
+	}
+
+	oldest, ok := d.outbox.Oldest(chatID)
+	return ok && !schedule.Next(oldest).After(now)
+}
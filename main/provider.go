@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// FareQuery описывает запрос к поставщику цен: одно направление на один месяц
+type FareQuery struct {
+	Origin      string
+	Destination string
+	Month       string // Месяц вылета в формате "2006-01"
+	Currency    string
+	Direct      bool
+	Limit       int
+}
+
+// FareProvider - источник цен на авиабилеты.
+// Реализация получает запрос и возвращает нормализованный список рейсов
+// без какой-либо фильтрации по настройкам пользователя.
+type FareProvider interface {
+	Name() string
+	SearchFares(ctx context.Context, query FareQuery) ([]Flight, error)
+}
+
+// DestinationExplorer - поставщик, который умеет искать самые дешёвые направления
+// из города вылета. В запросе не задается Destination, результат - рейсы
+// в разные города, отсортированные по цене.
+type DestinationExplorer interface {
+	ExploreFares(ctx context.Context, query FareQuery) ([]Flight, error)
+}
+
+// errExploreUnsupported - поставщик не реализует DestinationExplorer
+var errExploreUnsupported = errors.New("поставщик не поддерживает поиск направлений")
+
+// exploreFares ищет направления, если поставщик это умеет
+func exploreFares(ctx context.Context, provider FareProvider, query FareQuery) ([]Flight, error) {
+	explorer, ok := provider.(DestinationExplorer)
+	if !ok {
+		return nil, errExploreUnsupported
+	}
+	return explorer.ExploreFares(ctx, query)
+}
+
+// ProviderErrorKind - класс ошибки поставщика
+type ProviderErrorKind string
+
+const (
+	ProviderErrRequest      ProviderErrorKind = "request"       // Не удалось собрать запрос
+	ProviderErrNetwork      ProviderErrorKind = "network"       // Сетевая ошибка или таймаут
+	ProviderErrRateLimit    ProviderErrorKind = "rate_limit"    // HTTP 429, слишком много запросов
+	ProviderErrServer       ProviderErrorKind = "server"        // HTTP 5xx на стороне поставщика
+	ProviderErrAuth         ProviderErrorKind = "auth"          // Неверный или просроченный токен
+	ProviderErrInvalidQuery ProviderErrorKind = "invalid_query" // Некорректные параметры, например код IATA
+	ProviderErrHTTP         ProviderErrorKind = "http"          // Прочие ответы с кодом, отличным от 200
+	ProviderErrDecode       ProviderErrorKind = "decode"        // Некорректный JSON в ответе
+	ProviderErrAPI          ProviderErrorKind = "api"           // API вернул success=false
+)
+
+// ProviderError - типизированная ошибка поставщика цен
+type ProviderError struct {
+	Provider   string
+	Kind       ProviderErrorKind
+	StatusCode int
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %s ошибка (HTTP %d): %v", e.Provider, e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %s ошибка: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable сообщает, имеет ли смысл повторить запрос.
+// Повторяются только временные сбои: сеть, таймауты, 429, 5xx и обрыв ответа.
+func (e *ProviderError) Retryable() bool {
+	switch e.Kind {
+	case ProviderErrNetwork, ProviderErrRateLimit, ProviderErrServer, ProviderErrDecode:
+		return true
+	default:
+		return false
+	}
+}
+
+// Description возвращает понятное пользователю описание ошибки
+func (e *ProviderError) Description() string {
+	switch e.Kind {
+	case ProviderErrNetwork:
+		return "сетевая ошибка"
+	case ProviderErrRateLimit:
+		return "превышен лимит запросов"
+	case ProviderErrServer:
+		return "сервис поставщика недоступен"
+	case ProviderErrAuth:
+		return "неверный токен API"
+	case ProviderErrInvalidQuery:
+		return "некорректный маршрут или код аэропорта"
+	case ProviderErrDecode:
+		return "некорректный ответ сервиса"
+	default:
+		return e.Err.Error()
+	}
+}
+
+// classifyHTTPStatus определяет класс ошибки по коду ответа
+func classifyHTTPStatus(statusCode int) ProviderErrorKind {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ProviderErrRateLimit
+	case statusCode >= 500:
+		return ProviderErrServer
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ProviderErrAuth
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return ProviderErrInvalidQuery
+	default:
+		return ProviderErrHTTP
+	}
+}
+
+// newFareProviders создает поставщиков цен из списка FARE_PROVIDERS
+func newFareProviders(config *AppConfig) ([]FareProvider, error) {
+	var providers []FareProvider
+
+	// Один HTTP-клиент на всех поставщиков, чтобы переиспользовать соединения
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	for _, name := range config.FareProviders {
+		var provider FareProvider
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case "travelpayouts":
+			provider = NewTravelpayoutsProvider(client, config.TravelPayoutsUrlPrice, config.TravelPayoutsUrlLatest, config.TravelPayoutsToken)
+		default:
+			return nil, fmt.Errorf("неизвестный поставщик цен: %s", name)
+		}
+
+		// Каждая повторная попытка тоже проходит через лимитер
+		providers = append(providers, &retryingProvider{
+			FareProvider: &rateLimitedProvider{
+				FareProvider: provider,
+				limiter:      NewRateLimiter(config.ProviderRateLimit, config.ProviderBurst),
+			},
+			policy: config.ProviderRetry,
+		})
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("не настроен ни один поставщик цен")
+	}
+	return providers, nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProvider - поставщик цен для тестов: отвечает функцией fares
+// и запоминает все запросы
+type fakeProvider struct {
+	name  string
+	fares func(query FareQuery) ([]Flight, error)
+
+	mu    sync.Mutex
+	calls []FareQuery
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) SearchFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, query)
+	p.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.fares == nil {
+		return nil, nil
+	}
+	return p.fares(query)
+}
+
+func (p *fakeProvider) ExploreFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	return p.SearchFares(ctx, query)
+}
+
+// Calls возвращает запросы в виде "OVB-AER-2026-11" в порядке сортировки
+func (p *fakeProvider) Calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	calls := make([]string, 0, len(p.calls))
+	for _, call := range p.calls {
+		calls = append(calls, fmt.Sprintf("%s-%s-%s", call.Origin, call.Destination, call.Month))
+	}
+	sort.Strings(calls)
+	return calls
+}
+
+// fakeFlight - рейс на 15-е число месяца запроса
+func fakeFlight(query FareQuery, price int, airline string) Flight {
+	month, err := time.Parse("2006-01", query.Month)
+	if err != nil {
+		month = time.Now()
+	}
+	departure := time.Date(month.Year(), month.Month(), 15, 9, 30, 0, 0, time.UTC)
+	return newFlight(query.Origin, query.Destination, departure, price, airline, "https://example.com", 240, 0)
+}
+
+func newTestFlightSearch(providers ...FareProvider) *FlightSearch {
+	config := &AppConfig{
+		OriginIATA:      []string{"OVB"},
+		DestinationIATA: "AER",
+		MonthsToSearch:  2,
+		SearchWorkers:   4,
+	}
+	return NewFlightSearch(config, providers, nil)
+}
+
+func TestSearchFansOutToEveryProviderMonthAndDirection(t *testing.T) {
+	first := &fakeProvider{name: "first"}
+	second := &fakeProvider{name: "second"}
+	search := newTestFlightSearch(first, second)
+
+	result, err := search.Search(context.Background(), search.DefaultQuery())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	var want []string
+	for _, month := range result.Months {
+		want = append(want, "OVB-AER-"+month, "AER-OVB-"+month)
+	}
+	sort.Strings(want)
+
+	for _, provider := range []*fakeProvider{first, second} {
+		got := provider.Calls()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: запросы %v, ожидались %v", provider.name, got, want)
+		}
+	}
+	if len(result.Months) != 2 {
+		t.Errorf("месяцев поиска %d, ожидалось 2", len(result.Months))
+	}
+}
+
+func TestSearchMergesProvidersAndKeepsCheapestDuplicate(t *testing.T) {
+	first := &fakeProvider{name: "first", fares: func(query FareQuery) ([]Flight, error) {
+		return []Flight{fakeFlight(query, 12000, "S7"), fakeFlight(query, 15000, "SU")}, nil
+	}}
+	second := &fakeProvider{name: "second", fares: func(query FareQuery) ([]Flight, error) {
+		// Тот же рейс S7 дешевле и рейс, которого нет у первого поставщика
+		return []Flight{fakeFlight(query, 11000, "S7"), fakeFlight(query, 9000, "U6")}, nil
+	}}
+	search := newTestFlightSearch(first, second)
+
+	result, err := search.Search(context.Background(), search.DefaultQuery())
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	// На каждый месяц - три разных рейса туда, дубль S7 остается по меньшей цене
+	if got, want := len(result.Outbound), 3*len(result.Months); got != want {
+		t.Fatalf("рейсов туда %d, ожидалось %d: %+v", got, want, result.Outbound)
+	}
+	for _, flight := range result.Outbound {
+		if flight.Airline == "S7" && flight.Price != 11000 {
+			t.Errorf("рейс S7 %s за %d₽, ожидалась меньшая цена 11000₽", flight.DepartureDate, flight.Price)
+		}
+	}
+	if got, want := len(result.Return), 3*len(result.Months); got != want {
+		t.Errorf("обратных рейсов %d, ожидалось %d", got, want)
+	}
+}
+
+func TestSearchReportsFailedLegs(t *testing.T) {
+	failing := &fakeProvider{name: "failing", fares: func(query FareQuery) ([]Flight, error) {
+		return nil, &ProviderError{Provider: "failing", Kind: ProviderErrAuth, StatusCode: 401, Err: errors.New("bad token")}
+	}}
+	working := &fakeProvider{name: "working", fares: func(query FareQuery) ([]Flight, error) {
+		return []Flight{fakeFlight(query, 10000, "S7")}, nil
+	}}
+
+	search := newTestFlightSearch(failing, working)
+	result, err := search.Search(context.Background(), search.DefaultQuery())
+	if err != nil {
+		t.Fatalf("Search с одним рабочим поставщиком: %v", err)
+	}
+	if len(result.Outbound) == 0 {
+		t.Error("рейсы рабочего поставщика потерялись")
+	}
+	if got, want := len(result.Errors), len(failing.Calls()); got != want {
+		t.Fatalf("ошибок %d, ожидалось по одной на запрос неисправного поставщика (%d)", got, want)
+	}
+	for _, legErr := range result.Errors {
+		if legErr.Provider != "failing" || !legErr.Permanent() || legErr.Description() != "неверный токен API" {
+			t.Errorf("неожиданная ошибка плеча: %+v (%s)", legErr, legErr.Description())
+		}
+	}
+
+	// Если не ответил ни один поставщик, поиск завершается ошибкой
+	search = newTestFlightSearch(failing)
+	if _, err := search.Search(context.Background(), search.DefaultQuery()); err == nil {
+		t.Error("поиск без единого успешного запроса должен вернуть ошибку")
+	}
+}
+
+func TestSearchWithoutLegsReturnsError(t *testing.T) {
+	search := newTestFlightSearch()
+	if _, err := search.Search(context.Background(), search.DefaultQuery()); err == nil {
+		t.Error("поиск без единого запроса к поставщикам должен вернуть ошибку")
+	}
+}
+
+// testLegs - запросы OVB → AER к provider на count месяцев подряд с ноября 2026
+func testLegs(provider FareProvider, count int) []searchLeg {
+	legs := make([]searchLeg, 0, count)
+	for i := 0; i < count; i++ {
+		month := time.Date(2026, time.November+time.Month(i), 1, 0, 0, 0, 0, time.UTC)
+		legs = append(legs, searchLeg{provider: provider, origin: "OVB", destination: "AER", month: month.Format("2006-01")})
+	}
+	return legs
+}
+
+func TestRunLegsLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	provider := &fakeProvider{name: "slow", fares: func(query FareQuery) ([]Flight, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil, nil
+	}}
+	search := &FlightSearch{providers: []FareProvider{provider}, workers: 2}
+
+	search.runLegs(context.Background(), testLegs(provider, 8), time.Now())
+
+	if len(provider.Calls()) != 8 {
+		t.Errorf("выполнено %d запросов из 8", len(provider.Calls()))
+	}
+	if maxInFlight != 2 {
+		t.Errorf("одновременно выполнялось до %d запросов, ожидалось 2 по числу воркеров", maxInFlight)
+	}
+}
+
+func TestRunLegsKeepsResultsInLegOrder(t *testing.T) {
+	// Ранние месяцы отвечают дольше, поэтому запросы завершаются в обратном порядке
+	provider := &fakeProvider{name: "fake", fares: func(query FareQuery) ([]Flight, error) {
+		month, _ := time.Parse("2006-01", query.Month)
+		offset := (month.Year()-2026)*12 + int(month.Month()) - int(time.November)
+		time.Sleep(time.Duration(6-offset) * 10 * time.Millisecond)
+		return []Flight{fakeFlight(query, 10000, "S7")}, nil
+	}}
+	search := &FlightSearch{providers: []FareProvider{provider}, workers: 4}
+
+	legs := search.runLegs(context.Background(), testLegs(provider, 6), time.Now())
+	for i, leg := range legs {
+		if len(leg.flights) != 1 || leg.flights[0].DepartureAt.Format("2006-01") != leg.month {
+			t.Errorf("запрос %d (%s): получены рейсы %+v", i, leg.month, leg.flights)
+		}
+	}
+}
+
+func TestRunLegsStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &fakeProvider{name: "fake", fares: func(query FareQuery) ([]Flight, error) {
+		cancel()
+		return nil, context.Canceled
+	}}
+	search := &FlightSearch{providers: []FareProvider{provider}, workers: 1}
+
+	legs := search.runLegs(ctx, testLegs(provider, 5), time.Now())
+	if calls := provider.Calls(); len(calls) != 1 {
+		t.Errorf("после отмены выполнено запросов: %d, ожидался 1", len(calls))
+	}
+	for i, leg := range legs {
+		if !errors.Is(leg.err, context.Canceled) {
+			t.Errorf("запрос %d: ошибка %v, ожидался context.Canceled", i, leg.err)
+		}
+	}
+}
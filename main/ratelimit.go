@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter - потокобезопасный token bucket: в среднем rate запросов в секунду
+// с возможностью кратковременного всплеска до burst запросов
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   ratePerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait блокируется, пока не появится свободный токен или не отменится контекст.
+// При rate <= 0 ограничение отключено.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve забирает токен, если он есть, иначе возвращает время до появления следующего
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// rateLimitedProvider ограничивает частоту запросов к поставщику.
+// Лимит общий для всех воркеров поиска.
+type rateLimitedProvider struct {
+	FareProvider
+	limiter *RateLimiter
+}
+
+func (p *rateLimitedProvider) SearchFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.FareProvider.SearchFares(ctx, query)
+}
+
+func (p *rateLimitedProvider) ExploreFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	// Не тратим лимит на поставщика, который все равно откажет
+	if _, ok := p.FareProvider.(DestinationExplorer); !ok {
+		return nil, errExploreUnsupported
+	}
+	if err := p.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return exploreFares(ctx, p.FareProvider, query)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitTime - сколько длился вызов limiter.Wait
+func waitTime(t *testing.T, limiter *RateLimiter, ctx context.Context) time.Duration {
+	t.Helper()
+	started := time.Now()
+	if err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	return time.Since(started)
+}
+
+func TestRateLimiterAllowsBurst(t *testing.T) {
+	limiter := NewRateLimiter(10, 3)
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		waitTime(t, limiter, context.Background())
+	}
+	if elapsed := time.Since(started); elapsed > 50*time.Millisecond {
+		t.Errorf("всплеск из 3 запросов занял %s, ожидалось без ожидания", elapsed)
+	}
+
+	// Четвертый запрос ждет пополнения: 1 токен при 10 в секунду - около 100 мс
+	if elapsed := waitTime(t, limiter, context.Background()); elapsed < 50*time.Millisecond {
+		t.Errorf("запрос сверх всплеска прошел за %s, ожидалось ожидание", elapsed)
+	}
+}
+
+func TestRateLimiterWaitsForRefill(t *testing.T) {
+	limiter := NewRateLimiter(20, 1)
+	waitTime(t, limiter, context.Background())
+
+	elapsed := waitTime(t, limiter, context.Background())
+	if elapsed < 30*time.Millisecond || elapsed > time.Second {
+		t.Errorf("ожидание токена %s, ожидалось около 50 мс", elapsed)
+	}
+
+	// За время простоя токены копятся, но не больше burst
+	time.Sleep(120 * time.Millisecond)
+	waitTime(t, limiter, context.Background())
+	if elapsed := waitTime(t, limiter, context.Background()); elapsed < 30*time.Millisecond {
+		t.Errorf("после простоя накоплено больше burst: второй запрос прошел за %s", elapsed)
+	}
+}
+
+func TestRateLimiterStopsWaitingOnCancel(t *testing.T) {
+	limiter := NewRateLimiter(0.1, 1) // следующий токен только через 10 секунд
+	waitTime(t, limiter, context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	err := limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ошибка %v, ожидался context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("отмена контекста прервала ожидание только через %s", elapsed)
+	}
+}
+
+func TestRateLimitedProviderSkipsRequestOnCancel(t *testing.T) {
+	fake := &fakeProvider{name: "fake"}
+	provider := &rateLimitedProvider{FareProvider: fake, limiter: NewRateLimiter(0.1, 1)}
+	query := FareQuery{Origin: "OVB", Destination: "AER", Month: "2026-11"}
+
+	if _, err := provider.SearchFares(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := provider.SearchFares(ctx, query); !errors.Is(err, context.Canceled) {
+		t.Errorf("ошибка %v, ожидался context.Canceled", err)
+	}
+	if calls := fake.Calls(); len(calls) != 1 {
+		t.Errorf("запросов к поставщику %d, ожидался 1: ожидание лимита отменено", len(calls))
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy - повтор с экспоненциальной задержкой и случайным разбросом
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// retryableError реализуют ошибки, которые сами знают, стоит ли их повторять
+type retryableError interface {
+	Retryable() bool
+}
+
+// isRetryable сообщает, что ошибку можно повторить. Ошибки без классификации
+// считаются постоянными, отмена контекста не повторяется никогда.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var re retryableError
+	return errors.As(err, &re) && re.Retryable()
+}
+
+// Do вызывает fn, пока она не завершится успешно, не вернет постоянную ошибку
+// или не закончатся попытки
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil || !isRetryable(err) || attempt == attempts {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		fmt.Printf("Повтор через %s (попытка %d из %d): %v\n", delay.Round(time.Millisecond), attempt+1, attempts, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	return err
+}
+
+// backoff возвращает задержку перед попыткой attempt+1: BaseDelay * 2^(attempt-1),
+// но не больше MaxDelay, со случайным разбросом в пределах половины значения
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryingProvider повторяет временные ошибки поставщика
+type retryingProvider struct {
+	FareProvider
+	policy RetryPolicy
+}
+
+func (p *retryingProvider) SearchFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	var flights []Flight
+	err := p.policy.Do(ctx, func() error {
+		var err error
+		flights, err = p.FareProvider.SearchFares(ctx, query)
+		return err
+	})
+	return flights, err
+}
+
+func (p *retryingProvider) ExploreFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	var flights []Flight
+	err := p.policy.Do(ctx, func() error {
+		var err error
+		flights, err = exploreFares(ctx, p.FareProvider, query)
+		return err
+	})
+	return flights, err
+}
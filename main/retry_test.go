@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProviderErrorRetryable(t *testing.T) {
+	tests := []struct {
+		kind      ProviderErrorKind
+		retryable bool
+	}{
+		{ProviderErrNetwork, true},
+		{ProviderErrRateLimit, true},
+		{ProviderErrServer, true},
+		{ProviderErrDecode, true},
+		{ProviderErrRequest, false},
+		{ProviderErrAuth, false},
+		{ProviderErrInvalidQuery, false},
+		{ProviderErrHTTP, false},
+		{ProviderErrAPI, false},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("плечо: %w", &ProviderError{Provider: "fake", Kind: tt.kind, Err: errors.New("сбой")})
+		if got := isRetryable(err); got != tt.retryable {
+			t.Errorf("%s: isRetryable = %v, ожидалось %v", tt.kind, got, tt.retryable)
+		}
+	}
+
+	if isRetryable(context.DeadlineExceeded) || isRetryable(errors.New("без классификации")) {
+		t.Error("таймаут и неклассифицированные ошибки не повторяются")
+	}
+}
+
+func TestClassifyHTTPStatus(t *testing.T) {
+	tests := map[int]ProviderErrorKind{
+		http.StatusTooManyRequests:     ProviderErrRateLimit,
+		http.StatusInternalServerError: ProviderErrServer,
+		http.StatusBadGateway:          ProviderErrServer,
+		http.StatusUnauthorized:        ProviderErrAuth,
+		http.StatusForbidden:           ProviderErrAuth,
+		http.StatusBadRequest:          ProviderErrInvalidQuery,
+		http.StatusUnprocessableEntity: ProviderErrInvalidQuery,
+		http.StatusNotFound:            ProviderErrHTTP,
+	}
+	for status, want := range tests {
+		if got := classifyHTTPStatus(status); got != want {
+			t.Errorf("HTTP %d: %s, ожидалось %s", status, got, want)
+		}
+	}
+}
+
+func TestTravelpayoutsErrorClassification(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   ProviderErrorKind
+	}{
+		{"лимит запросов", http.StatusTooManyRequests, "slow down", ProviderErrRateLimit},
+		{"сбой сервера", http.StatusServiceUnavailable, "", ProviderErrServer},
+		{"неверный токен", http.StatusUnauthorized, "unauthorized", ProviderErrAuth},
+		{"битый JSON", http.StatusOK, "{", ProviderErrDecode},
+		{"success=false по токену", http.StatusOK, `{"success":false,"error":"Invalid token"}`, ProviderErrAuth},
+		{"success=false по коду IATA", http.StatusOK, `{"success":false,"error":"unknown destination"}`, ProviderErrInvalidQuery},
+		{"success=false прочее", http.StatusOK, `{"success":false,"error":"try later"}`, ProviderErrAPI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewTravelpayoutsProvider(server.Client(), server.URL, server.URL, "token")
+			_, err := provider.SearchFares(context.Background(), FareQuery{Origin: "OVB", Destination: "AER", Month: "2026-11"})
+
+			var providerErr *ProviderError
+			if !errors.As(err, &providerErr) {
+				t.Fatalf("ожидалась ProviderError, получено %v", err)
+			}
+			if providerErr.Kind != tt.kind {
+				t.Errorf("класс ошибки %s, ожидался %s", providerErr.Kind, tt.kind)
+			}
+		})
+	}
+}
+
+func TestRetryingProviderRetriesOnlyTemporaryErrors(t *testing.T) {
+	attempts := 0
+	flaky := &fakeProvider{name: "flaky", fares: func(query FareQuery) ([]Flight, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, &ProviderError{Provider: "flaky", Kind: ProviderErrServer, StatusCode: 502, Err: errors.New("bad gateway")}
+		}
+		return []Flight{fakeFlight(query, 10000, "S7")}, nil
+	}}
+	provider := &retryingProvider{FareProvider: flaky, policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+
+	flights, err := provider.SearchFares(context.Background(), FareQuery{Origin: "OVB", Destination: "AER", Month: "2026-11"})
+	if err != nil || len(flights) != 1 || attempts != 2 {
+		t.Fatalf("после временного сбоя: рейсов %d, попыток %d, ошибка %v", len(flights), attempts, err)
+	}
+
+	attempts = 0
+	permanent := &fakeProvider{name: "permanent", fares: func(query FareQuery) ([]Flight, error) {
+		attempts++
+		return nil, &ProviderError{Provider: "permanent", Kind: ProviderErrAuth, StatusCode: 401, Err: errors.New("bad token")}
+	}}
+	provider = &retryingProvider{FareProvider: permanent, policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}}
+	if _, err := provider.SearchFares(context.Background(), FareQuery{}); err == nil || attempts != 1 {
+		t.Errorf("постоянная ошибка повторена: попыток %d, ошибка %v", attempts, err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Itinerary - поездка туда-обратно из двух рейсов
+type Itinerary struct {
+	Outbound   Flight
+	Return     Flight
+	Nights     int
+	TotalPrice int
+}
+
+// BuildItineraries составляет поездки из рейсов туда и обратно: обратный рейс
+// возвращается в тот же город вылета (аэропорт может отличаться), вылетает после прилета туда, а число ночей
+// укладывается в [minNights, maxNights]. Поездки дороже maxPrice (если задана)
+// отбрасываются, остальные сортируются по общей цене.
+func BuildItineraries(outbound []Flight, inbound []Flight, minNights int, maxNights int, maxPrice int) []Itinerary {
+	var itineraries []Itinerary
+
+	for _, out := range outbound {
+		arrival := out.DepartureAt.Add(time.Duration(out.Duration) * time.Minute)
+
+		for _, back := range inbound {
+			// Возврат в тот же город, но не обязательно в тот же аэропорт
+			if airports.CityCode(back.Origin) != airports.CityCode(out.Destination) ||
+				airports.CityCode(back.Destination) != airports.CityCode(out.Origin) {
+				continue
+			}
+			if !back.DepartureAt.After(arrival) {
+				continue
+			}
+
+			nights := nightsBetween(out.DepartureAt, back.DepartureAt)
+			if nights < minNights || (maxNights > 0 && nights > maxNights) {
+				continue
+			}
+
+			total := out.Price + back.Price
+			if maxPrice > 0 && total > maxPrice {
+				continue
+			}
+
+			itineraries = append(itineraries, Itinerary{
+				Outbound:   out,
+				Return:     back,
+				Nights:     nights,
+				TotalPrice: total,
+			})
+		}
+	}
+
+	sort.SliceStable(itineraries, func(i, j int) bool {
+		return itineraries[i].TotalPrice < itineraries[j].TotalPrice
+	})
+	return itineraries
+}
+
+// nightsBetween считает число ночей между календарными датами вылетов
+// (по местному времени каждого аэропорта)
+func nightsBetween(from time.Time, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
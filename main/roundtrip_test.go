@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// tripFlight - рейс origin → destination с вылетом departure ("2006-01-02 15:04", UTC)
+func tripFlight(origin, destination, departure string, price int) Flight {
+	flight := testFlight(departure, 300, 0, "S7")
+	flight.Origin, flight.Destination, flight.Price = origin, destination, price
+	return flight
+}
+
+func TestBuildItineraries(t *testing.T) {
+	out := tripFlight("OVB", "AER", "2026-11-13 10:00", 10000) // прилёт в 15:00 UTC
+
+	tests := []struct {
+		name      string
+		outbound  []Flight
+		inbound   []Flight
+		minNights int
+		maxNights int
+		maxPrice  int
+		want      []string // "ночей:цена" в порядке результата
+	}{
+		{
+			name:     "обратный рейс в тот же город",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2026-11-20 10:00", 8000)},
+			want:     []string{"7:18000"},
+		},
+		{
+			name:     "другой аэропорт того же города",
+			outbound: []Flight{tripFlight("SVO", "AER", "2026-11-13 10:00", 5000)},
+			inbound:  []Flight{tripFlight("AER", "VKO", "2026-11-16 10:00", 6000), tripFlight("AER", "MOW", "2026-11-17 10:00", 7000)},
+			want:     []string{"3:11000", "4:12000"},
+		},
+		{
+			name:     "возвращение в другой город или из другого города",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "KJA", "2026-11-20 10:00", 8000), tripFlight("LED", "OVB", "2026-11-20 10:00", 8000)},
+			want:     nil,
+		},
+		{
+			name:     "обратный рейс вылетает раньше прилёта туда",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2026-11-13 14:00", 8000), tripFlight("AER", "OVB", "2026-11-13 15:00", 8000)},
+			want:     nil,
+		},
+		{
+			name:     "обратный рейс в тот же день после прилёта",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2026-11-13 18:00", 8000)},
+			want:     []string{"0:18000"},
+		},
+		{
+			name:     "границы числа ночей включаются",
+			outbound: []Flight{out},
+			inbound: []Flight{
+				tripFlight("AER", "OVB", "2026-11-15 10:00", 1000), // 2 ночи
+				tripFlight("AER", "OVB", "2026-11-16 10:00", 2000), // 3
+				tripFlight("AER", "OVB", "2026-11-18 10:00", 3000), // 5
+				tripFlight("AER", "OVB", "2026-11-19 10:00", 4000), // 6
+			},
+			minNights: 3,
+			maxNights: 5,
+			want:      []string{"3:12000", "5:13000"},
+		},
+		{
+			name:     "без максимума ночей",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2027-01-13 10:00", 8000)},
+			want:     []string{"61:18000"},
+		},
+		{
+			name:     "общая цена не больше maxPrice",
+			outbound: []Flight{out},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2026-11-20 10:00", 5000), tripFlight("AER", "OVB", "2026-11-21 10:00", 5001)},
+			maxPrice: 15000,
+			want:     []string{"7:15000"},
+		},
+		{
+			name:     "сортировка по общей цене, при равной - в исходном порядке",
+			outbound: []Flight{out, tripFlight("OVB", "AER", "2026-11-14 10:00", 7000)},
+			inbound:  []Flight{tripFlight("AER", "OVB", "2026-11-20 10:00", 9000), tripFlight("AER", "OVB", "2026-11-21 10:00", 6000)},
+			want:     []string{"7:13000", "8:16000", "6:16000", "7:19000"},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, itinerary := range BuildItineraries(tt.outbound, tt.inbound, tt.minNights, tt.maxNights, tt.maxPrice) {
+			got = append(got, fmt.Sprintf("%d:%d", itinerary.Nights, itinerary.TotalPrice))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNightsBetweenUsesLocalDates(t *testing.T) {
+	novosibirsk, err := time.LoadLocation("Asia/Novosibirsk")
+	if err != nil {
+		t.Skip(err)
+	}
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		from, to time.Time
+		want     int
+	}{
+		// 23:30 в Новосибирске и 01:00 следующего дня в Москве - одна ночь,
+		// хотя по UTC между вылетами меньше суток
+		{time.Date(2026, 11, 13, 23, 30, 0, 0, novosibirsk), time.Date(2026, 11, 14, 1, 0, 0, 0, moscow), 1},
+		{time.Date(2026, 11, 13, 6, 0, 0, 0, novosibirsk), time.Date(2026, 11, 13, 22, 0, 0, 0, moscow), 0},
+		{time.Date(2026, 12, 28, 10, 0, 0, 0, time.UTC), time.Date(2027, 1, 4, 10, 0, 0, 0, time.UTC), 7},
+		{time.Date(2027, 3, 27, 10, 0, 0, 0, time.UTC), time.Date(2027, 3, 29, 9, 0, 0, 0, time.UTC), 2},
+	}
+	for _, tt := range tests {
+		if got := nightsBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s → %s: %d ночей, ожидалось %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
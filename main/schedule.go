@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// defaultSchedule - расписание подписок, если не задано другое: каждый день в 10:00
+const defaultSchedule = "0 10 * * *"
+
+// minScheduleInterval - подписка проверяется не чаще раза в час,
+// чтобы не расходовать лимиты поставщиков цен
+const minScheduleInterval = time.Hour
+
+// scheduleParser разбирает cron-выражения из пяти полей и описатели вида "@every 6h"
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// cronWeekdays - номера дней недели в cron по названиям Flight.DayOfWeek
+var cronWeekdays = map[string]int{"Вс": 0, "Пн": 1, "Вт": 2, "Ср": 3, "Чт": 4, "Пт": 5, "Сб": 6}
+
+// ParseSchedule разбирает расписание подписки и возвращает cron-выражение.
+// Кроме cron-выражения понимает готовые варианты:
+//
+//	hourly                 каждый час
+//	daily [ЧЧ:ММ]          каждый день, по умолчанию в 10:00
+//	weekly [день] [ЧЧ:ММ]  раз в неделю, по умолчанию в понедельник в 10:00
+//	every 6h               через равные промежутки
+func ParseSchedule(value string) (string, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("расписание не задано")
+	}
+
+	var spec string
+	switch preset := strings.ToLower(fields[0]); preset {
+	case "hourly", "ежечасно":
+		if len(fields) > 1 {
+			return "", fmt.Errorf("у hourly нет параметров")
+		}
+		spec = "0 * * * *"
+
+	case "daily", "ежедневно":
+		if len(fields) > 2 {
+			return "", fmt.Errorf("нужно: daily [ЧЧ:ММ]")
+		}
+		clock := "10:00"
+		if len(fields) == 2 {
+			clock = fields[1]
+		}
+		hour, minute, err := parseClock(clock)
+		if err != nil {
+			return "", err
+		}
+		spec = fmt.Sprintf("%d %d * * *", minute, hour)
+
+	case "weekly", "еженедельно":
+		if len(fields) > 3 {
+			return "", fmt.Errorf("нужно: weekly [день] [ЧЧ:ММ]")
+		}
+		day, clock := "Пн", "10:00"
+		for _, field := range fields[1:] {
+			if name, ok := weekdayNames[strings.ToLower(field)]; ok {
+				day = name
+			} else {
+				clock = field
+			}
+		}
+		hour, minute, err := parseClock(clock)
+		if err != nil {
+			return "", err
+		}
+		spec = fmt.Sprintf("%d %d * * %d", minute, hour, cronWeekdays[day])
+
+	case "every", "каждые":
+		if len(fields) != 2 {
+			return "", fmt.Errorf("нужно: every 6h")
+		}
+		interval, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return "", fmt.Errorf("некорректный интервал: %s", fields[1])
+		}
+		spec = "@every " + interval.String()
+
+	default:
+		spec = strings.Join(fields, " ")
+	}
+
+	if err := validateSchedule(spec); err != nil {
+		return "", err
+	}
+	return spec, nil
+}
+
+// validateSchedule проверяет, что расписание разбирается и срабатывает не чаще minScheduleInterval
+func validateSchedule(spec string) error {
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		return fmt.Errorf("часовой пояс задается отдельно: /schedule tz")
+	}
+
+	schedule, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("некорректное расписание %q: %v", spec, err)
+	}
+
+	// Несколько срабатываний подряд ловят и неравномерные выражения вроде "0,30 9 * * *"
+	next := schedule.Next(time.Now())
+	for i := 0; i < 24; i++ {
+		following := schedule.Next(next)
+		if following.Sub(next) < minScheduleInterval {
+			return fmt.Errorf("расписание срабатывает слишком часто, минимум раз в %s", formatInterval(minScheduleInterval))
+		}
+		next = following
+	}
+	return nil
+}
+
+func parseClock(value string) (hour, minute int, err error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("некорректное время: %s", value)
+	}
+	return clock.Hour(), clock.Minute(), nil
+}
+
+// ParseTimezone проверяет название часового пояса: "Asia/Novosibirsk", "UTC"
+func ParseTimezone(name string) (string, error) {
+	location, err := time.LoadLocation(name)
+	if err != nil || name == "" || strings.EqualFold(name, "Local") {
+		return "", fmt.Errorf("неизвестный часовой пояс: %s, нужно название вроде Asia/Novosibirsk", name)
+	}
+	return location.String(), nil
+}
+
+// DescribeSchedule описывает cron-выражение по-русски; для выражений,
+// не похожих на готовые варианты, возвращает само выражение
+func DescribeSchedule(spec string) string {
+	if interval, found := strings.CutPrefix(spec, "@every "); found {
+		if duration, err := time.ParseDuration(interval); err == nil {
+			return "каждые " + formatInterval(duration)
+		}
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 || fields[2] != "*" || fields[3] != "*" {
+		return "по расписанию " + spec
+	}
+	minute, minuteErr := strconv.Atoi(fields[0])
+	if minuteErr != nil {
+		return "по расписанию " + spec
+	}
+
+	if fields[1] == "*" && fields[4] == "*" {
+		if minute == 0 {
+			return "каждый час"
+		}
+		return fmt.Sprintf("каждый час в %02d мин.", minute)
+	}
+
+	hour, hourErr := strconv.Atoi(fields[1])
+	if hourErr != nil {
+		return "по расписанию " + spec
+	}
+	clock := fmt.Sprintf("%02d:%02d", hour, minute)
+
+	if fields[4] == "*" {
+		return "каждый день в " + clock
+	}
+	if day, err := strconv.Atoi(fields[4]); err == nil {
+		for name, number := range cronWeekdays {
+			if number == day%7 {
+				return fmt.Sprintf("каждую неделю, %s в %s", name, clock)
+			}
+		}
+	}
+	return "по расписанию " + spec
+}
+
+// formatInterval - интервал в часах и минутах: "6 ч", "1 ч 30 мин"
+func formatInterval(interval time.Duration) string {
+	hours, minutes := int(interval.Hours()), int(interval.Minutes())%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d мин", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%d ч", hours)
+	default:
+		return fmt.Sprintf("%d ч %d мин", hours, minutes)
+	}
+}
+
+// Scheduler запускает проверку подписок по их расписаниям. Подписки с одинаковым
+// расписанием и часовым поясом проверяются одним заданием, последовательно.
+// Задания пересобираются при каждом изменении подписок.
+type Scheduler struct {
+	cron          *cron.Cron
+	subscriptions *SubscriptionStore
+	defaultSpec   string
+	defaultTZ     string
+	run           func(subs []Subscription)
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID // Полное расписание "CRON_TZ=... выражение" -> задание
+}
+
+func NewScheduler(config *AppConfig, subscriptions *SubscriptionStore) *Scheduler {
+	return &Scheduler{
+		cron:          cron.New(cron.WithParser(scheduleParser)),
+		subscriptions: subscriptions,
+		defaultSpec:   config.Schedule,
+		defaultTZ:     config.ScheduleTimezone,
+		entries:       make(map[string]cron.EntryID),
+	}
+}
+
+// Start создает задания по текущим подпискам и запускает планировщик.
+// run вызывается со всеми подписками, расписание которых сработало.
+func (s *Scheduler) Start(run func(subs []Subscription)) {
+	s.run = run
+	s.Reload()
+	s.subscriptions.OnChange(s.Reload)
+	s.cron.Start()
+}
+
+// Stop останавливает планировщик и дожидается завершения запущенных проверок
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Schedule возвращает расписание подписки и часовой пояс, в котором оно действует
+func (s *Scheduler) Schedule(sub Subscription) (spec string, timezone string) {
+	spec, timezone = sub.Schedule, s.subscriptions.Settings(sub.ChatID).Timezone
+	if spec == "" {
+		spec = s.defaultSpec
+	}
+	if timezone == "" {
+		timezone = s.defaultTZ
+	}
+	return spec, timezone
+}
+
+// Defaults возвращает расписание и часовой пояс по умолчанию
+func (s *Scheduler) Defaults() (spec string, timezone string) {
+	return s.defaultSpec, s.defaultTZ
+}
+
+// Location - часовой пояс, в котором чату показываются и выполняются расписания
+func (s *Scheduler) Location(chatID int64) *time.Location {
+	_, timezone := s.Schedule(Subscription{ChatID: chatID})
+	if location, err := time.LoadLocation(timezone); err == nil && timezone != "" {
+		return location
+	}
+	return time.Local
+}
+
+// key - полное расписание подписки для cron, с часовым поясом
+func (s *Scheduler) key(sub Subscription) string {
+	return withTimezone(s.Schedule(sub))
+}
+
+// withTimezone добавляет к cron-выражению часовой пояс, пустой - время сервера
+func withTimezone(spec, timezone string) string {
+	if timezone == "" {
+		return spec
+	}
+	return "CRON_TZ=" + timezone + " " + spec
+}
+
+// Reload приводит задания в соответствие с подписками: убирает расписания,
+// которые больше не используются, и добавляет новые
+func (s *Scheduler) Reload() {
+	used := make(map[string]bool)
+	for _, sub := range s.subscriptions.All() {
+		used[s.key(sub)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, id := range s.entries {
+		if !used[key] {
+			s.cron.Remove(id)
+			delete(s.entries, key)
+		}
+	}
+	for key := range used {
+		if _, exists := s.entries[key]; exists {
+			continue
+		}
+		key := key // go 1.21: замыкание иначе захватит общую переменную цикла
+		id, err := s.cron.AddFunc(key, func() { s.runKey(key) })
+		if err != nil {
+			log.Printf("❌ Некорректное расписание %q: %v", key, err)
+			continue
+		}
+		s.entries[key] = id
+	}
+}
+
+// runKey проверяет подписки, у которых сейчас это расписание
+func (s *Scheduler) runKey(key string) {
+	var subs []Subscription
+	for _, sub := range s.subscriptions.All() {
+		if s.key(sub) == key {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) > 0 {
+		s.run(subs)
+	}
+}
+
+// Next возвращает время следующей проверки подписки
+func (s *Scheduler) Next(sub Subscription) (time.Time, bool) {
+	s.mu.Lock()
+	id, exists := s.entries[s.key(sub)]
+	s.mu.Unlock()
+
+	if !exists {
+		return time.Time{}, false
+	}
+	// До первого прохода планировщика время следующего запуска не вычислено
+	entry := s.cron.Entry(id)
+	if entry.Next.IsZero() && entry.Schedule != nil {
+		entry.Next = entry.Schedule.Next(time.Now())
+	}
+	return entry.Next, !entry.Next.IsZero()
+}
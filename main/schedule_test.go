@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReloadRunsEachScheduleWithItsOwnSubscriptions(t *testing.T) {
+	subscriptions, err := NewSubscriptionStore(filepath.Join(t.TempDir(), "subscriptions.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []Subscription{
+		{ChatID: 1, Destination: "AER", Schedule: "0 9 * * *"},
+		{ChatID: 2, Destination: "DXB", Schedule: "0 18 * * *"},
+		{ChatID: 3, Destination: "BKK"}, // расписание по умолчанию
+	} {
+		if _, err := subscriptions.Add(sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scheduler := NewScheduler(&AppConfig{Schedule: defaultSchedule}, subscriptions)
+	var mu sync.Mutex
+	var runs []string
+	scheduler.run = func(subs []Subscription) {
+		var destinations []string
+		for _, sub := range subs {
+			destinations = append(destinations, scheduler.key(sub)+"="+sub.Destination)
+		}
+		mu.Lock()
+		runs = append(runs, strings.Join(destinations, ","))
+		mu.Unlock()
+	}
+	scheduler.Reload()
+
+	if len(scheduler.entries) != 3 {
+		t.Fatalf("заданий %d, ожидалось 3: %v", len(scheduler.entries), scheduler.entries)
+	}
+
+	// Задания запускаются так же, как их запускает cron
+	for key, id := range scheduler.entries {
+		entry := scheduler.cron.Entry(id)
+		if entry.Job == nil {
+			t.Fatalf("задание %q не зарегистрировано в cron", key)
+		}
+		entry.Job.Run()
+	}
+
+	sort.Strings(runs)
+	want := []string{"0 10 * * *=BKK", "0 18 * * *=DXB", "0 9 * * *=AER"}
+	if strings.Join(runs, "; ") != strings.Join(want, "; ") {
+		t.Errorf("запуски заданий %q, ожидалось %q", runs, want)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Subscription - отслеживаемый маршрут конкретного чата
+type Subscription struct {
+	ID             int        `json:"id"`
+	ChatID         int64      `json:"chat_id"`
+	ThreadID       int        `json:"thread_id,omitempty"` // Тема форума, в которой создана подписка
+	Origins        []string   `json:"origins"`
+	Destination    string     `json:"destination"`
+	MaxPrice       int        `json:"max_price"`
+	MonthsToSearch int        `json:"months"`
+	DateFilter     DateFilter `json:"date_filter"`
+	RoundTrip      bool       `json:"round_trip,omitempty"`
+	MinNights      int        `json:"min_nights,omitempty"`
+	MaxNights      int        `json:"max_nights,omitempty"`
+	Schedule       string     `json:"schedule,omitempty"` // cron-выражение, пустое - расписание по умолчанию
+	Sinks          []string   `json:"sinks,omitempty"`    // Каналы уведомлений, пустой список - каналы по умолчанию
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ChatSettings - настройки чата, общие для всех его подписок
+type ChatSettings struct {
+	Timezone   string     `json:"timezone,omitempty"`    // Часовой пояс расписаний, пустой - по умолчанию
+	QuietHours TimeWindow `json:"quiet_hours,omitempty"` // Уведомления в это время откладываются
+	Digest     string     `json:"digest,omitempty"`      // cron-выражение дайджеста, пустое - уведомлять сразу
+
+	// Фильтры рейсов чата, nil - фильтры по умолчанию из настроек бота
+	Filters *FareFilters `json:"filters,omitempty"`
+}
+
+// Query подставляет настройки чата в параметры поиска по умолчанию
+func (c ChatSettings) Query(defaults SearchQuery) SearchQuery {
+	query := defaults
+	if c.Filters != nil {
+		query.Filters = c.Filters.Clone()
+	}
+	return query
+}
+
+// Query собирает параметры поиска подписки поверх настроек её чата
+// и значений по умолчанию
+func (s Subscription) Query(defaults SearchQuery, settings ChatSettings) SearchQuery {
+	query := settings.Query(defaults)
+	query.Origins = append([]string{}, s.Origins...)
+	query.Destination = s.Destination
+	if s.MaxPrice > 0 {
+		query.MaxPrice = s.MaxPrice
+	}
+	if s.MonthsToSearch > 0 {
+		query.MonthsToSearch = s.MonthsToSearch
+	}
+	if s.DateFilter.Enabled {
+		query.DateFilter = s.DateFilter
+	}
+	if s.RoundTrip {
+		query.RoundTrip = true
+		query.MinNights = s.MinNights
+		query.MaxNights = s.MaxNights
+	}
+	return query
+}
+
+// SubscriptionStore хранит подписки и настройки всех чатов в JSON-файле
+type SubscriptionStore struct {
+	mu            sync.RWMutex
+	path          string
+	listeners     []func()
+	NextID        int                    `json:"next_id"`
+	Subscriptions []Subscription         `json:"subscriptions"`
+	Chats         map[int64]ChatSettings `json:"chats,omitempty"`
+}
+
+func NewSubscriptionStore(path string) (*SubscriptionStore, error) {
+	store := &SubscriptionStore{
+		path:   path,
+		NextID: 1,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("чтение подписок: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("разбор подписок: %w", err)
+	}
+	return store, nil
+}
+
+// Add сохраняет новую подписку и возвращает её с присвоенным номером
+func (s *SubscriptionStore) Add(sub Subscription) (Subscription, error) {
+	defer s.notify()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub.ID = s.NextID
+	sub.CreatedAt = time.Now()
+	s.NextID++
+	s.Subscriptions = append(s.Subscriptions, sub)
+
+	return sub, s.save()
+}
+
+// Remove удаляет подписку чата по номеру. Чужие подписки удалить нельзя.
+func (s *SubscriptionStore) Remove(chatID int64, id int) (bool, error) {
+	defer s.notify()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, sub := range s.Subscriptions {
+		if sub.ID == id && sub.ChatID == chatID {
+			s.Subscriptions = append(s.Subscriptions[:i], s.Subscriptions[i+1:]...)
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+// SetSchedule меняет расписание подписки чата, при id = 0 - всех подписок чата.
+// Пустое расписание возвращает расписание по умолчанию. Возвращает число
+// изменённых подписок.
+func (s *SubscriptionStore) SetSchedule(chatID int64, id int, spec string) (int, error) {
+	return s.update(chatID, id, func(sub *Subscription) {
+		sub.Schedule = spec
+	})
+}
+
+// SetSinks меняет каналы уведомлений подписки чата, при id = 0 - всех подписок
+// чата. Пустой список возвращает каналы по умолчанию. Возвращает число
+// изменённых подписок.
+func (s *SubscriptionStore) SetSinks(chatID int64, id int, sinks []string) (int, error) {
+	return s.update(chatID, id, func(sub *Subscription) {
+		sub.Sinks = append([]string(nil), sinks...)
+	})
+}
+
+// update применяет change к подписке чата, при id = 0 - ко всем подпискам чата
+func (s *SubscriptionStore) update(chatID int64, id int, change func(sub *Subscription)) (int, error) {
+	defer s.notify()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := 0
+	for i := range s.Subscriptions {
+		sub := &s.Subscriptions[i]
+		if sub.ChatID == chatID && (id == 0 || sub.ID == id) {
+			change(sub)
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, s.save()
+}
+
+// Settings возвращает настройки чата
+func (s *SubscriptionStore) Settings(chatID int64) ChatSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.Chats[chatID]
+	if settings.Filters != nil {
+		filters := settings.Filters.Clone()
+		settings.Filters = &filters
+	}
+	return settings
+}
+
+// UpdateSettings меняет настройки чата функцией update и сохраняет их
+func (s *SubscriptionStore) UpdateSettings(chatID int64, update func(settings *ChatSettings)) error {
+	defer s.notify()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Chats == nil {
+		s.Chats = make(map[int64]ChatSettings)
+	}
+	settings := s.Chats[chatID]
+	update(&settings)
+	s.Chats[chatID] = settings
+
+	return s.save()
+}
+
+// OnChange подписывает fn на изменения подписок и настроек чатов.
+// fn вызывается после снятия блокировки и может читать хранилище.
+func (s *SubscriptionStore) OnChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, fn)
+}
+
+func (s *SubscriptionStore) notify() {
+	s.mu.RLock()
+	listeners := append([]func(){}, s.listeners...)
+	s.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
+}
+
+// ForChat возвращает копии подписок чата
+func (s *SubscriptionStore) ForChat(chatID int64) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []Subscription
+	for _, sub := range s.Subscriptions {
+		if sub.ChatID == chatID {
+			result = append(result, sub)
+		}
+	}
+	return result
+}
+
+// All возвращает копии всех подписок
+func (s *SubscriptionStore) All() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Subscription, len(s.Subscriptions))
+	copy(result, s.Subscriptions)
+	return result
+}
+
+// save атомарно перезаписывает файл подписок. Вызывается под блокировкой.
+func (s *SubscriptionStore) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("создание каталога подписок: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("запись подписок: %w", err)
+	}
+	return os.Rename(tmpPath, s.path)
+}
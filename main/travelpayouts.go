@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIResponse struct {
+	Data []struct {
+		Origin             string `json:"origin"`
+		Destination        string `json:"destination"`
+		OriginAirport      string `json:"origin_airport"`
+		DestinationAirport string `json:"destination_airport"`
+		DepartureAt        string `json:"departure_at"`
+		Price              int    `json:"price"`
+		Airline            string `json:"airline"`
+		Link               string `json:"link"`
+		Duration           int    `json:"duration"`
+		Transfers          int    `json:"transfers"`
+	} `json:"data"`
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+}
+
+// latestPricesResponse - ответ метода get_latest_prices: цены, найденные
+// пользователями Aviasales за последние дни, без времени вылета и ссылки
+type latestPricesResponse struct {
+	Data []struct {
+		Origin          string `json:"origin"`
+		Destination     string `json:"destination"`
+		DepartDate      string `json:"depart_date"`
+		Value           int    `json:"value"`
+		NumberOfChanges int    `json:"number_of_changes"`
+		Duration        int    `json:"duration"`
+	} `json:"data"`
+	Error   string `json:"error"`
+	Success bool   `json:"success"`
+}
+
+// TravelpayoutsProvider получает цены через метод prices_for_dates API Travelpayouts,
+// а самые дешёвые направления - через get_latest_prices
+type TravelpayoutsProvider struct {
+	client    *http.Client
+	apiURL    string
+	latestURL string
+	token     string
+}
+
+func NewTravelpayoutsProvider(client *http.Client, apiURL string, latestURL string, token string) *TravelpayoutsProvider {
+	return &TravelpayoutsProvider{
+		client:    client,
+		apiURL:    apiURL,
+		latestURL: latestURL,
+		token:     token,
+	}
+}
+
+func (p *TravelpayoutsProvider) Name() string {
+	return "travelpayouts"
+}
+
+func (p *TravelpayoutsProvider) SearchFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	currency := query.Currency
+	if currency == "" {
+		currency = "rub"
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 30
+	}
+
+	params := url.Values{}
+	params.Add("origin", query.Origin)
+	params.Add("destination", query.Destination)
+	params.Add("currency", currency)
+	params.Add("departure_at", query.Month)
+	params.Add("sorting", "price")
+	params.Add("direct", strconv.FormatBool(query.Direct))
+	params.Add("limit", strconv.Itoa(limit))
+	params.Add("one_way", "true")
+	params.Add("token", p.token)
+
+	var apiResponse APIResponse
+	if err := p.get(ctx, p.apiURL, params, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if !apiResponse.Success {
+		return nil, p.error(classifyAPIError(apiResponse.Error), 0, errors.New(apiResponse.Error))
+	}
+
+	flights := make([]Flight, 0, len(apiResponse.Data))
+	for _, flightData := range apiResponse.Data {
+		departureTime, err := time.Parse(time.RFC3339, flightData.DepartureAt)
+		if err != nil {
+			// Пропускаем только некорректную запись, а не весь ответ
+			continue
+		}
+
+		// Для таблицы важен конкретный аэропорт, поле origin может содержать код города
+		origin := firstNonEmpty(flightData.OriginAirport, query.Origin, flightData.Origin)
+		destination := firstNonEmpty(flightData.DestinationAirport, query.Destination, flightData.Destination)
+
+		flights = append(flights, newFlight(origin, destination, departureTime, flightData.Price,
+			flightData.Airline, "https://aviasales.ru"+flightData.Link, flightData.Duration, flightData.Transfers))
+	}
+
+	return flights, nil
+}
+
+// ExploreFares ищет самые дешёвые направления из query.Origin на месяц query.Month
+func (p *TravelpayoutsProvider) ExploreFares(ctx context.Context, query FareQuery) ([]Flight, error) {
+	currency := query.Currency
+	if currency == "" {
+		currency = "rub"
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 1000
+	}
+
+	params := url.Values{}
+	params.Add("origin", query.Origin)
+	params.Add("currency", currency)
+	params.Add("beginning_of_period", query.Month+"-01")
+	params.Add("period_type", "month")
+	params.Add("one_way", "true")
+	params.Add("sorting", "price")
+	params.Add("limit", strconv.Itoa(limit))
+	params.Add("token", p.token)
+
+	var apiResponse latestPricesResponse
+	if err := p.get(ctx, p.latestURL, params, &apiResponse); err != nil {
+		return nil, err
+	}
+
+	if !apiResponse.Success {
+		return nil, p.error(classifyAPIError(apiResponse.Error), 0, errors.New(apiResponse.Error))
+	}
+
+	flights := make([]Flight, 0, len(apiResponse.Data))
+	for _, data := range apiResponse.Data {
+		if query.Direct && data.NumberOfChanges > 0 {
+			continue
+		}
+		departureDate, err := time.Parse("2006-01-02", data.DepartDate)
+		if err != nil {
+			continue
+		}
+
+		// Ссылки в ответе нет, ведем на поиск Aviasales: OVB1511BKK1 - откуда, день и месяц, куда, взрослых
+		link := fmt.Sprintf("https://www.aviasales.ru/search/%s%s%s1", data.Origin, departureDate.Format("0201"), data.Destination)
+		flights = append(flights, newFlight(data.Origin, data.Destination, departureDate, data.Value,
+			"", link, data.Duration, data.NumberOfChanges))
+	}
+
+	return flights, nil
+}
+
+// get выполняет GET-запрос к API и декодирует JSON-ответ в target
+func (p *TravelpayoutsProvider) get(ctx context.Context, apiURL string, params url.Values, target any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return p.error(ProviderErrRequest, 0, err)
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return p.error(ProviderErrNetwork, 0, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Тело ошибки часто объясняет причину (например, неизвестный код IATA)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = resp.Status
+		}
+		return p.error(classifyHTTPStatus(resp.StatusCode), resp.StatusCode, errors.New(message))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return p.error(ProviderErrDecode, 0, err)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// classifyAPIError определяет класс ошибки по тексту ответа с success=false
+func classifyAPIError(message string) ProviderErrorKind {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "token") || strings.Contains(lower, "unauthorized"):
+		return ProviderErrAuth
+	case strings.Contains(lower, "origin") || strings.Contains(lower, "destination") || strings.Contains(lower, "iata"):
+		return ProviderErrInvalidQuery
+	default:
+		return ProviderErrAPI
+	}
+}
+
+func (p *TravelpayoutsProvider) error(kind ProviderErrorKind, statusCode int, err error) *ProviderError {
+	return &ProviderError{
+		Provider:   p.Name(),
+		Kind:       kind,
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
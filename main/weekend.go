@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DepartureWindow - день недели и интервал времени вылета. Сравнивается
+// с уже вычисленными Flight.DayOfWeek и Flight.DepartureTime.
+type DepartureWindow struct {
+	Day  string // День недели как в Flight.DayOfWeek: "Пт"
+	From string // Время "15:04", включительно
+	To   string
+}
+
+// Matches проверяет, что рейс вылетает в этот день и в этот интервал
+func (w DepartureWindow) Matches(flight Flight) bool {
+	return flight.DayOfWeek == w.Day && flight.DepartureTime >= w.From && flight.DepartureTime <= w.To
+}
+
+func (w DepartureWindow) String() string {
+	if w.From == "00:00" && w.To == "23:59" {
+		return w.Day
+	}
+	return fmt.Sprintf("%s %s–%s", w.Day, w.From, w.To)
+}
+
+// WeekendRules - когда можно улетать и когда возвращаться в поездке на выходные
+type WeekendRules struct {
+	Outbound []DepartureWindow
+	Return   []DepartureWindow
+}
+
+// DefaultWeekendRules - вылет в пятницу вечером или в субботу утром,
+// возвращение в воскресенье или понедельник
+var DefaultWeekendRules = WeekendRules{
+	Outbound: []DepartureWindow{
+		{Day: "Пт", From: "17:00", To: "23:59"},
+		{Day: "Сб", From: "00:00", To: "12:00"},
+	},
+	Return: []DepartureWindow{
+		{Day: "Вс", From: "00:00", To: "23:59"},
+		{Day: "Пн", From: "00:00", To: "23:59"},
+	},
+}
+
+func (r WeekendRules) String() string {
+	return fmt.Sprintf("туда: %s; обратно: %s", joinWindows(r.Outbound), joinWindows(r.Return))
+}
+
+func joinWindows(windows []DepartureWindow) string {
+	parts := make([]string, 0, len(windows))
+	for _, window := range windows {
+		parts = append(parts, window.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func matchesAnyWindow(windows []DepartureWindow, flight Flight) bool {
+	for _, window := range windows {
+		if window.Matches(flight) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdayNames - названия дней недели для настроек: русские сокращения и английские
+var weekdayNames = map[string]string{
+	"пн": "Пн", "вт": "Вт", "ср": "Ср", "чт": "Чт", "пт": "Пт", "сб": "Сб", "вс": "Вс",
+	"mon": "Пн", "tue": "Вт", "wed": "Ср", "thu": "Чт", "fri": "Пт", "sat": "Сб", "sun": "Вс",
+}
+
+// ParseDepartureWindows разбирает окна вылета: "Пт 17:00-23:59, Сб 00:00-12:00".
+// День без времени означает весь день: "Вс, Пн".
+func ParseDepartureWindows(value string) ([]DepartureWindow, error) {
+	var windows []DepartureWindow
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+
+		day, ok := weekdayNames[strings.ToLower(fields[0])]
+		if !ok {
+			return nil, fmt.Errorf("неизвестный день недели: %s", fields[0])
+		}
+		window := DepartureWindow{Day: day, From: "00:00", To: "23:59"}
+
+		if len(fields) > 1 {
+			from, to, found := strings.Cut(fields[1], "-")
+			if !found {
+				return nil, fmt.Errorf("некорректный интервал времени: %s", fields[1])
+			}
+			for _, clock := range []string{from, to} {
+				if _, err := time.Parse("15:04", clock); err != nil {
+					return nil, fmt.Errorf("некорректное время: %s", clock)
+				}
+			}
+			window.From, window.To = from, to
+		}
+		windows = append(windows, window)
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("не задано ни одного дня")
+	}
+	return windows, nil
+}
+
+// maxWeekends - на сколько выходных вперёд можно искать за один раз
+const maxWeekends = 12
+
+// upcomingWeekends возвращает пятницы ближайших count выходных, начиная с этой недели
+func upcomingWeekends(now time.Time, count int) []time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	friday := today.AddDate(0, 0, (int(time.Friday)-int(today.Weekday())+7)%7)
+
+	fridays := make([]time.Time, 0, count)
+	for i := 0; i < count; i++ {
+		fridays = append(fridays, friday.AddDate(0, 0, 7*i))
+	}
+	return fridays
+}
+
+// WeekendTrip - самая дешёвая поездка на одни выходные
+type WeekendTrip struct {
+	Friday time.Time
+	Itinerary
+}
+
+// FindWeekendTrips выбирает для каждых выходных из fridays самую дешёвую поездку,
+// у которой оба рейса укладываются в правила. Выходные без поездок пропускаются.
+func FindWeekendTrips(itineraries []Itinerary, fridays []time.Time, rules WeekendRules) []WeekendTrip {
+	cheapest := make(map[time.Time]Itinerary)
+
+	for _, trip := range itineraries {
+		if !matchesAnyWindow(rules.Outbound, trip.Outbound) || !matchesAnyWindow(rules.Return, trip.Return) {
+			continue
+		}
+
+		// Выходные определяются ближайшей к вылету пятницей
+		date := trip.Outbound.DepartureAt
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(time.Friday) - int(day.Weekday()) + 7) % 7
+		if offset > 3 {
+			offset -= 7
+		}
+		friday := day.AddDate(0, 0, offset)
+
+		if current, exists := cheapest[friday]; !exists || trip.TotalPrice < current.TotalPrice {
+			cheapest[friday] = trip
+		}
+	}
+
+	var trips []WeekendTrip
+	for _, friday := range fridays {
+		if trip, exists := cheapest[friday]; exists {
+			trips = append(trips, WeekendTrip{Friday: friday, Itinerary: trip})
+		}
+	}
+	return trips
+}
+
+// WeekendReport - поездки на выходные в одно направление
+type WeekendReport struct {
+	Destination string
+	Fridays     []time.Time
+	Trips       []WeekendTrip // По одной самой дешёвой на выходные
+	Errors      []LegError
+	Err         error // Поиск по направлению не удался целиком
+}
+
+// Cheapest возвращает самую дешёвую поездку среди всех выходных
+func (r WeekendReport) Cheapest() (WeekendTrip, bool) {
+	if len(r.Trips) == 0 {
+		return WeekendTrip{}, false
+	}
+	best := r.Trips[0]
+	for _, trip := range r.Trips[1:] {
+		if trip.TotalPrice < best.TotalPrice {
+			best = trip
+		}
+	}
+	return best, true
+}
+
+// SearchWeekends ищет поездки на ближайшие weekends выходных из городов вылета
+// запроса в каждое из направлений. Поиск туда-обратно выполняется обычным Search
+// на окне вокруг выбранных выходных, затем поездки отбираются по правилам.
+func (fs *FlightSearch) SearchWeekends(ctx context.Context, query SearchQuery, destinations []string, weekends int, rules WeekendRules) ([]WeekendReport, error) {
+	if weekends < 1 || weekends > maxWeekends {
+		return nil, fmt.Errorf("число выходных должно быть от 1 до %d, а не %d", maxWeekends, weekends)
+	}
+	fridays := upcomingWeekends(time.Now(), weekends)
+
+	// Окно с запасом в три дня в обе стороны от пятниц, чтобы правила
+	// могли разрешать и вылет в четверг, и возвращение во вторник
+	query = query.Clone()
+	query.RoundTrip = true
+	query.MinNights, query.MaxNights = 1, 5
+	query.DateFilter = DateFilter{
+		StartDate: fridays[0].AddDate(0, 0, -3),
+		EndDate:   fridays[len(fridays)-1].AddDate(0, 0, 3),
+		Enabled:   true,
+		Mode:      "range",
+	}
+
+	reports := make([]WeekendReport, 0, len(destinations))
+	for _, destination := range destinations {
+		query.Destination = destination
+		report := WeekendReport{Destination: destination, Fridays: fridays}
+
+		result, err := fs.Search(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			report.Err = err
+			reports = append(reports, report)
+			continue
+		}
+
+		report.Trips = FindWeekendTrips(result.Itineraries, fridays, rules)
+		report.Errors = result.Errors
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testDate(value string) time.Time {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
+
+func TestUpcomingWeekends(t *testing.T) {
+	tests := []struct {
+		now   string
+		count int
+		want  string
+	}{
+		{"2026-11-11 12:00", 2, "[2026-11-13 2026-11-20]"}, // среда
+		{"2026-11-13 23:30", 1, "[2026-11-13]"},            // сама пятница
+		{"2026-11-14 08:00", 2, "[2026-11-20 2026-11-27]"}, // суббота - уже следующие выходные
+		{"2026-12-27 10:00", 2, "[2027-01-01 2027-01-08]"}, // через Новый год
+		{"2026-11-11 12:00", 0, "[]"},
+	}
+	for _, tt := range tests {
+		now, err := time.Parse("2006-01-02 15:04", tt.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, friday := range upcomingWeekends(now, tt.count) {
+			if friday.Weekday() != time.Friday {
+				t.Errorf("%s: %s - не пятница", tt.now, friday.Format("2006-01-02"))
+			}
+			got = append(got, friday.Format("2006-01-02"))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s, %d выходных: %v, ожидалось %s", tt.now, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestParseDepartureWindows(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{"Пт 17:00-23:59, Сб 00:00-12:00", "Пт 17:00–23:59, Сб 00:00–12:00", false},
+		{"вс, mon", "Вс, Пн", false},
+		{" , сб 06:00-10:00 ,", "Сб 06:00–10:00", false},
+		{"", "", true},
+		{"пятница 17:00-23:59", "", true},
+		{"пт 17:00", "", true},
+		{"пт 17:00-25:00", "", true},
+	}
+	for _, tt := range tests {
+		windows, err := ParseDepartureWindows(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: ошибка %v", tt.value, err)
+			continue
+		}
+		if !tt.err && joinWindows(windows) != tt.want {
+			t.Errorf("%q: %v, ожидалось %s", tt.value, windows, tt.want)
+		}
+	}
+}
+
+// testTrip - поездка с вылетом туда и обратно в указанное время UTC
+func testTrip(outbound, back string, price int) Itinerary {
+	there := testFlight(outbound, 300, 0, "S7")
+	home := testFlight(back, 300, 0, "S7")
+	home.Origin, home.Destination = there.Destination, there.Origin
+	return Itinerary{Outbound: there, Return: home, TotalPrice: price}
+}
+
+func TestFindWeekendTripsSnapsToNearestFriday(t *testing.T) {
+	anyDay := func(days ...string) []DepartureWindow {
+		var windows []DepartureWindow
+		for _, day := range days {
+			windows = append(windows, DepartureWindow{Day: day, From: "00:00", To: "23:59"})
+		}
+		return windows
+	}
+	everyDay := anyDay("Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс")
+	rules := WeekendRules{Outbound: everyDay, Return: everyDay}
+	fridays := []time.Time{testDate("2026-11-13"), testDate("2026-11-20")}
+
+	// Вылет до трёх дней после пятницы относится к её выходным,
+	// со вторника - уже к следующим
+	tests := []struct {
+		departure string
+		friday    string
+	}{
+		{"2026-11-12 10:00", "2026-11-13"}, // четверг
+		{"2026-11-13 18:00", "2026-11-13"}, // пятница
+		{"2026-11-14 09:00", "2026-11-13"}, // суббота
+		{"2026-11-15 09:00", "2026-11-13"}, // воскресенье
+		{"2026-11-16 09:00", "2026-11-13"}, // понедельник
+		{"2026-11-17 09:00", "2026-11-20"}, // вторник
+		{"2026-11-18 09:00", "2026-11-20"}, // среда
+	}
+	for _, tt := range tests {
+		trip := testTrip(tt.departure, "2026-11-22 18:00", 10000)
+		trips := FindWeekendTrips([]Itinerary{trip}, fridays, rules)
+		if len(trips) != 1 || trips[0].Friday.Format("2006-01-02") != tt.friday {
+			t.Errorf("вылет %s: выходные %+v, ожидалась пятница %s", tt.departure, trips, tt.friday)
+		}
+	}
+}
+
+func TestFindWeekendTrips(t *testing.T) {
+	fridays := []time.Time{testDate("2026-11-13"), testDate("2026-11-20")}
+	itineraries := []Itinerary{
+		testTrip("2026-11-13 18:00", "2026-11-15 18:00", 15000), // пятница вечером - воскресенье
+		testTrip("2026-11-14 08:00", "2026-11-16 08:00", 12000), // суббота утром - понедельник, дешевле
+		testTrip("2026-11-13 10:00", "2026-11-15 18:00", 5000),  // пятница днём - рано по правилам
+		testTrip("2026-11-14 08:00", "2026-11-17 08:00", 4000),  // возвращение во вторник
+		testTrip("2026-11-06 18:00", "2026-11-08 18:00", 3000),  // выходные не из списка
+	}
+
+	trips := FindWeekendTrips(itineraries, fridays, DefaultWeekendRules)
+	if len(trips) != 1 {
+		t.Fatalf("поездок %d, ожидалась одна: вторые выходные без подходящих рейсов: %+v", len(trips), trips)
+	}
+	if trip := trips[0]; !trip.Friday.Equal(fridays[0]) || trip.TotalPrice != 12000 {
+		t.Errorf("выбрана поездка за %d₽ на выходные %s, ожидалась самая дешёвая по правилам за 12000₽",
+			trip.TotalPrice, trip.Friday.Format("2006-01-02"))
+	}
+}
+
+func TestSearchWeekendsRejectsWeekendCount(t *testing.T) {
+	search := newTestFlightSearch(&fakeProvider{name: "fake"})
+	for _, weekends := range []int{0, -1, maxWeekends + 1} {
+		if _, err := search.SearchWeekends(context.Background(), search.DefaultQuery(), []string{"AER"}, weekends, DefaultWeekendRules); err == nil {
+			t.Errorf("%d выходных: ожидалась ошибка", weekends)
+		}
+	}
+}